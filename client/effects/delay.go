@@ -0,0 +1,62 @@
+package effects
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// the longest delay, sets the size of the delay line
+const maxDelay = 2.0
+
+// Delay repeats the sound after a while, each repeat quieter than the last
+type Delay struct {
+	sr beep.SampleRate
+
+	line [][2]float64
+	pos  int
+
+	// time is the delay in seconds, feedback how much of each repeat is repeated again,
+	// mix how much of the repeats is heard
+	time, feedback, mix float64
+}
+
+func NewDelay(sr beep.SampleRate) *Delay {
+	return &Delay{
+		sr:       sr,
+		line:     make([][2]float64, int(maxDelay*float64(sr))+1),
+		time:     0.3,
+		feedback: 0.35,
+		mix:      0.3,
+	}
+}
+
+func (d *Delay) Set(param string, value float64) error {
+	switch param {
+	case "time":
+		d.time = math.Max(1/float64(d.sr), math.Min(value, maxDelay))
+	case "feedback":
+		// at 1 or more the repeats would never stop
+		d.feedback = math.Max(0, math.Min(value, 0.95))
+	case "mix":
+		d.mix = math.Max(0, math.Min(value, 1))
+	default:
+		return unknown("delay", param)
+	}
+	return nil
+}
+
+func (d *Delay) Process(samples [][2]float64) {
+	n := len(d.line)
+	delay := int(d.time * float64(d.sr))
+
+	for i := range samples {
+		delayed := d.line[(d.pos-delay+n)%n]
+		for c := range samples[i] {
+			x := samples[i][c]
+			d.line[d.pos][c] = x + delayed[c]*d.feedback
+			samples[i][c] = x + delayed[c]*d.mix
+		}
+		d.pos = (d.pos + 1) % n
+	}
+}
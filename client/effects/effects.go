@@ -0,0 +1,176 @@
+// Package effects processes the sound of the client before it reaches the speakers:
+// filters, delay, reverb, soft clipping and limiting, chained one after the other.
+package effects
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Effect processes a block of samples in place
+type Effect interface {
+	Process(samples [][2]float64)
+	// Set changes a parameter while the effect is playing
+	Set(param string, value float64) error
+}
+
+// latent is an effect that holds the sound back, like a limiter looking ahead
+type latent interface {
+	Latency() time.Duration
+}
+
+// constructors create an effect with its default parameters
+var constructors = map[string]func(sr beep.SampleRate) Effect{
+	"lowpass":  func(sr beep.SampleRate) Effect { return NewFilter(sr, LowPass, 5000) },
+	"highpass": func(sr beep.SampleRate) Effect { return NewFilter(sr, HighPass, 100) },
+	"delay":    func(sr beep.SampleRate) Effect { return NewDelay(sr) },
+	"reverb":   func(sr beep.SampleRate) Effect { return NewReverb(sr) },
+	"softclip": func(sr beep.SampleRate) Effect { return NewSoftClip() },
+	"limiter":  func(sr beep.SampleRate) Effect { return NewLimiter(sr) },
+}
+
+// Names lists the effects that can be put in a chain
+func Names() []string {
+	names := make([]string, 0, len(constructors))
+	for name := range constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates an effect by name
+func New(sr beep.SampleRate, name string) (Effect, error) {
+	c, ok := constructors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown effect %q (expected %s)", name, strings.Join(Names(), ", "))
+	}
+	return c(sr), nil
+}
+
+// Chain runs the samples through every effect in order
+type Chain struct {
+	sr      beep.SampleRate
+	names   []string
+	effects []Effect
+}
+
+// Parse builds a chain from a spec like "highpass:cutoff=120,q=0.7;softclip:drive=2".
+// An empty spec is an empty chain which leaves the sound alone.
+func Parse(sr beep.SampleRate, spec string) (*Chain, error) {
+	c := &Chain{sr: sr}
+
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, params := part, ""
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			name, params = part[:i], part[i+1:]
+		}
+
+		e, err := c.add(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range strings.Split(params, ",") {
+			if strings.TrimSpace(p) == "" {
+				continue
+			}
+
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("effect %s: parameter %q must be name=value", name, p)
+			}
+
+			v, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("effect %s: %w", name, err)
+			}
+
+			if err := e.Set(strings.TrimSpace(kv[0]), v); err != nil {
+				return nil, fmt.Errorf("effect %s: %w", name, err)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Chain) add(name string) (Effect, error) {
+	e, err := New(c.sr, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.names = append(c.names, name)
+	c.effects = append(c.effects, e)
+	return e, nil
+}
+
+// Set changes a parameter written as "effect.param" on the first effect with that name.
+// An effect that isn't in the chain yet is added to the end, so a server can build up the chain remotely.
+func (c *Chain) Set(param string, value float64) error {
+	i := strings.IndexByte(param, '.')
+	if i < 0 {
+		return fmt.Errorf("parameter %q must be effect.param", param)
+	}
+	name := param[:i]
+
+	for j, n := range c.names {
+		if n == name {
+			return c.effects[j].Set(param[i+1:], value)
+		}
+	}
+
+	e, err := c.add(name)
+	if err != nil {
+		return err
+	}
+	return e.Set(param[i+1:], value)
+}
+
+// Process runs the samples through the chain
+func (c *Chain) Process(samples [][2]float64) {
+	for _, e := range c.effects {
+		e.Process(samples)
+	}
+}
+
+// Latency is how long the chain holds the sound back
+func (c *Chain) Latency() time.Duration {
+	var d time.Duration
+	for _, e := range c.effects {
+		if l, ok := e.(latent); ok {
+			d += l.Latency()
+		}
+	}
+	return d
+}
+
+// Len is the number of effects in the chain
+func (c *Chain) Len() int {
+	return len(c.effects)
+}
+
+// Streamer plays s through the chain
+func (c *Chain) Streamer(s beep.Streamer) beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		n, ok = s.Stream(samples)
+		c.Process(samples[:n])
+		return n, ok
+	})
+}
+
+// unknown is the error for a parameter an effect doesn't have
+func unknown(effect, param string) error {
+	return fmt.Errorf("%s has no parameter %q", effect, param)
+}
@@ -0,0 +1,173 @@
+package effects
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+const sr = beep.SampleRate(48000)
+
+// gain plays a sine through the effect and measures how much of it comes out once it settles
+func gain(e Effect, freq float64) float64 {
+	samples := make([][2]float64, sr.N(1e9/2))
+	for i := range samples {
+		v := math.Sin(2 * math.Pi * freq * float64(i) / float64(sr))
+		samples[i] = [2]float64{v, v}
+	}
+
+	e.Process(samples)
+
+	var peak float64
+	for _, s := range samples[len(samples)/2:] {
+		peak = math.Max(peak, math.Abs(s[0]))
+	}
+	return peak
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		typ      FilterType
+		freq     float64
+		min, max float64
+	}{
+		{HighPass, 20, 0, 0.1},
+		{HighPass, 2000, 0.95, 1.05},
+		{LowPass, 200, 0.95, 1.05},
+		{LowPass, 15000, 0, 0.1},
+	}
+
+	for _, test := range tests {
+		cutoff := 200.0
+		if test.typ == LowPass {
+			cutoff = 2000
+		}
+
+		g := gain(NewFilter(sr, test.typ, cutoff), test.freq)
+		if g < test.min || g > test.max {
+			t.Errorf("Filter %d with cutoff %v: expected a gain between %v and %v at %vHz, got %v", test.typ, cutoff, test.min, test.max, test.freq, g)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	c, err := Parse(sr, "highpass:cutoff=120,q=0.7; softclip:drive=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Len() != 2 {
+		t.Fatalf("Expected 2 effects, got %d", c.Len())
+	}
+
+	// soft clipping keeps a full scale sine within full scale
+	if g := gain(c, 1000); g > 1 {
+		t.Errorf("Expected the chain to stay within full scale, got %v", g)
+	}
+
+	// setting a parameter of an effect that isn't there adds it
+	if err := c.Set("delay.time", 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Expected the delay to be added, got %d effects", c.Len())
+	}
+
+	bad := []string{"fuzz", "highpass:cutoff", "highpass:resonance=2", "delay:time=soon"}
+	for _, spec := range bad {
+		if _, err := Parse(sr, spec); err == nil {
+			t.Errorf("Expected %q to fail", spec)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	d := NewDelay(sr)
+	d.Set("time", 0.1)
+	d.Set("mix", 0.5)
+
+	// a single click comes back after the delay
+	samples := make([][2]float64, sr.N(1e9/2))
+	samples[0] = [2]float64{1, 1}
+	d.Process(samples)
+
+	echo := sr.N(1e8)
+	if samples[echo][0] != 0.5 {
+		t.Errorf("Expected an echo of 0.5 after %d samples, got %v", echo, samples[echo][0])
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(sr)
+
+	// a quiet sine with a burst far over full scale in the middle
+	samples := make([][2]float64, sr.N(1e9/2))
+	for i := range samples {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(sr))
+		if i > len(samples)/2 && i < len(samples)/2+sr.N(1e9/20) {
+			v *= 4
+		}
+		samples[i] = [2]float64{v, v}
+	}
+	l.Process(samples)
+
+	var peak float64
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(s[0]))
+	}
+	if peak > l.ceiling+1e-9 {
+		t.Errorf("Expected the limiter to stay under %v, got %v", l.ceiling, peak)
+	}
+
+	// the quiet part before the burst, past the look-ahead, is untouched
+	quiet := gainOf(samples[sr.N(1e9/10) : len(samples)/2-sr.N(1e9/100)])
+	if quiet < 0.49 || quiet > 0.51 {
+		t.Errorf("Expected the quiet part to stay at 0.5, got %v", quiet)
+	}
+
+	// every peak of the burst goes over, but together they are one clip
+	if clips, p := l.Clips(); clips != 1 || p < 1.99 {
+		t.Errorf("Expected 1 clip with a peak of 2, got %d with %v", clips, p)
+	}
+	if clips, _ := l.Clips(); clips != 0 {
+		t.Errorf("Expected Clips to start over, got %d", clips)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	c, err := Parse(sr, "lowpass;limiter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the look-ahead of the limiter holds the sound back
+	if l := c.Latency(); l != 5*time.Millisecond {
+		t.Errorf("Expected the chain to be 5ms late, got %v", l)
+	}
+
+	if err := c.Set("limiter.lookahead", 0.02); err != nil {
+		t.Fatal(err)
+	}
+	if l := c.Latency(); l != 20*time.Millisecond {
+		t.Errorf("Expected a longer look-ahead to be 20ms late, got %v", l)
+	}
+
+	// a click comes out exactly that late
+	l := NewLimiter(sr)
+	samples := make([][2]float64, sr.N(time.Second/10))
+	samples[0] = [2]float64{0.5, 0.5}
+	l.Process(samples)
+	if at := sr.N(l.Latency()); samples[at][0] != 0.5 {
+		t.Errorf("Expected the click %v late, got %v there", l.Latency(), samples[at])
+	}
+}
+
+func gainOf(samples [][2]float64) float64 {
+	var peak float64
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(s[0]))
+	}
+	return peak
+}
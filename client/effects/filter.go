@@ -0,0 +1,105 @@
+package effects
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// FilterType is the response of a biquad filter
+type FilterType int
+
+const (
+	LowPass FilterType = iota
+	HighPass
+)
+
+// coefficients are only recomputed every this many samples while the cutoff is modulated
+const modulationBlock = 32
+
+// Filter is a biquad low-pass or high-pass filter, from Robert Bristow-Johnson's audio EQ cookbook.
+// The cutoff can be swept up and down by a sine LFO.
+type Filter struct {
+	sr  beep.SampleRate
+	typ FilterType
+
+	cutoff, q float64
+	// rate is the frequency of the LFO in Hz, depth how far it sweeps the cutoff in octaves either way
+	rate, depth float64
+	lfo         float64
+	// samples until the coefficients are recomputed
+	block int
+
+	b0, b1, b2, a1, a2 float64
+	// the previous two inputs and outputs of each channel
+	x1, x2, y1, y2 [2]float64
+}
+
+func NewFilter(sr beep.SampleRate, typ FilterType, cutoff float64) *Filter {
+	f := &Filter{sr: sr, typ: typ, cutoff: cutoff, q: math.Sqrt2 / 2}
+	f.update(cutoff)
+	return f
+}
+
+func (f *Filter) Set(param string, value float64) error {
+	switch param {
+	case "cutoff":
+		f.cutoff = value
+	case "q":
+		f.q = math.Max(value, 0.1)
+	case "rate":
+		f.rate = value
+	case "depth":
+		f.depth = value
+	default:
+		return unknown("filter", param)
+	}
+
+	f.update(f.cutoff)
+	return nil
+}
+
+// update computes the coefficients for a cutoff
+func (f *Filter) update(cutoff float64) {
+	nyquist := float64(f.sr) / 2
+	cutoff = math.Max(10, math.Min(cutoff, nyquist*0.99))
+
+	w := 2 * math.Pi * cutoff / float64(f.sr)
+	alpha := math.Sin(w) / (2 * f.q)
+	cos := math.Cos(w)
+
+	a0 := 1 + alpha
+	switch f.typ {
+	case LowPass:
+		f.b0 = (1 - cos) / 2 / a0
+		f.b1 = (1 - cos) / a0
+		f.b2 = f.b0
+	case HighPass:
+		f.b0 = (1 + cos) / 2 / a0
+		f.b1 = -(1 + cos) / a0
+		f.b2 = f.b0
+	}
+	f.a1 = -2 * cos / a0
+	f.a2 = (1 - alpha) / a0
+}
+
+func (f *Filter) Process(samples [][2]float64) {
+	for i := range samples {
+		if f.rate > 0 && f.depth != 0 {
+			if f.block <= 0 {
+				f.update(f.cutoff * math.Pow(2, f.depth*math.Sin(2*math.Pi*f.lfo)))
+				_, f.lfo = math.Modf(f.lfo + f.rate*modulationBlock/float64(f.sr))
+				f.block = modulationBlock
+			}
+			f.block--
+		}
+
+		for c := range samples[i] {
+			x := samples[i][c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+			samples[i][c] = y
+		}
+	}
+}
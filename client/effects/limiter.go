@@ -0,0 +1,152 @@
+package effects
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// the longest look-ahead, sets the size of the buffers
+const maxLookahead = 0.05
+
+// going over full scale again within this long is counted as the same clip
+const clipGap = 100 * time.Millisecond
+
+// Limiter keeps the sound under a ceiling. It delays the sound a little so it sees peaks coming
+// and turns the gain down smoothly before they arrive, instead of flattening them as they pass.
+type Limiter struct {
+	sr beep.SampleRate
+
+	// the sound waiting to be played
+	line [][2]float64
+	pos  int
+
+	// hold keeps the lowest gain of the look-ahead, box averages it into a ramp that reaches it in time
+	hold     []held
+	box      []float64
+	boxSum   float64
+	gain     float64
+	position int64
+
+	// ceiling is the loudest sample let through, release how many seconds the gain takes to recover
+	ceiling, lookahead, release float64
+
+	// clips counts the times the sound went over full scale, lastOver is the position it last did
+	clips    int
+	lastOver int64
+	peak     float64
+}
+
+type held struct {
+	position int64
+	gain     float64
+}
+
+func NewLimiter(sr beep.SampleRate) *Limiter {
+	l := &Limiter{sr: sr, ceiling: 0.97, release: 0.1, lastOver: -int64(sr.N(clipGap)) - 1}
+	l.setLookahead(0.005)
+	return l
+}
+
+func (l *Limiter) setLookahead(seconds float64) {
+	l.lookahead = math.Max(1/float64(l.sr), math.Min(seconds, maxLookahead))
+	n := l.sr.N(time.Duration(l.lookahead * float64(time.Second)))
+	if n < 1 {
+		n = 1
+	}
+
+	l.line = make([][2]float64, n)
+	l.pos = 0
+	l.hold = l.hold[:0]
+	l.box = make([]float64, n)
+	for i := range l.box {
+		l.box[i] = 1
+	}
+	l.boxSum = float64(n)
+	l.gain = 1
+}
+
+func (l *Limiter) Set(param string, value float64) error {
+	switch param {
+	case "ceiling":
+		l.ceiling = math.Max(0.01, math.Min(value, 1))
+	case "lookahead":
+		l.setLookahead(value)
+	case "release":
+		l.release = math.Max(0.001, value)
+	default:
+		return unknown("limiter", param)
+	}
+	return nil
+}
+
+func (l *Limiter) Process(samples [][2]float64) {
+	n := len(l.line)
+	recovery := 1 - math.Exp(-1/(l.release*float64(l.sr)))
+
+	for i := range samples {
+		peak := math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1]))
+		l.count(peak)
+
+		target := 1.0
+		if peak > l.ceiling {
+			target = l.ceiling / peak
+		}
+
+		// the lowest gain needed by any sample still in the line, the newest included
+		for len(l.hold) > 0 && l.hold[len(l.hold)-1].gain >= target {
+			l.hold = l.hold[:len(l.hold)-1]
+		}
+		l.hold = append(l.hold, held{l.position, target})
+		if l.hold[0].position < l.position-int64(n) {
+			l.hold = l.hold[1:]
+		}
+
+		// averaging the held gain over the look-ahead ramps down to it exactly as the peak comes out
+		j := int(l.position % int64(n))
+		l.boxSum += l.hold[0].gain - l.box[j]
+		l.box[j] = l.hold[0].gain
+		ramp := math.Min(l.boxSum/float64(n), 1)
+
+		if ramp < l.gain {
+			l.gain = ramp
+		} else {
+			l.gain += (ramp - l.gain) * recovery
+		}
+
+		out := l.line[l.pos]
+		l.line[l.pos] = samples[i]
+		l.pos = (l.pos + 1) % n
+		l.position++
+
+		for c := range out {
+			samples[i][c] = math.Max(-l.ceiling, math.Min(out[c]*l.gain, l.ceiling))
+		}
+	}
+}
+
+// Latency is how long the look-ahead holds the sound back
+func (l *Limiter) Latency() time.Duration {
+	return l.sr.D(len(l.line))
+}
+
+// count keeps track of how often and how far the sound went over full scale
+func (l *Limiter) count(peak float64) {
+	l.peak = math.Max(l.peak, peak)
+	if peak <= 1 {
+		return
+	}
+
+	if l.position-l.lastOver > int64(l.sr.N(clipGap)) {
+		l.clips++
+	}
+	l.lastOver = l.position
+}
+
+// Clips reports how many times the sound went over full scale and its loudest sample since the last call
+func (l *Limiter) Clips() (clips int, peak float64) {
+	clips, peak = l.clips, l.peak
+	l.clips, l.peak = 0, 0
+	return clips, peak
+}
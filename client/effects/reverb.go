@@ -0,0 +1,98 @@
+package effects
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// delays of the combs and allpasses in samples at 44.1kHz, from freeverb
+var (
+	combTunings    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	allpassTunings = []int{556, 441, 341, 225}
+)
+
+// the right channel is tuned a little longer than the left so the reverb sounds wide
+const stereoSpread = 23
+
+// Reverb is a small Schroeder reverb in the style of freeverb: parallel damped combs followed by allpasses
+type Reverb struct {
+	combs     [2][]comb
+	allpasses [2][]allpass
+
+	// size sets how long the reverb rings, damp how fast the highs die out, mix how much of it is heard
+	size, damp, mix float64
+}
+
+type comb struct {
+	line  []float64
+	pos   int
+	store float64
+}
+
+type allpass struct {
+	line []float64
+	pos  int
+}
+
+func NewReverb(sr beep.SampleRate) *Reverb {
+	r := &Reverb{size: 0.7, damp: 0.4, mix: 0.2}
+
+	scale := float64(sr) / 44100
+	for c := range r.combs {
+		for _, t := range combTunings {
+			r.combs[c] = append(r.combs[c], comb{line: make([]float64, int(float64(t+c*stereoSpread)*scale))})
+		}
+		for _, t := range allpassTunings {
+			r.allpasses[c] = append(r.allpasses[c], allpass{line: make([]float64, int(float64(t+c*stereoSpread)*scale))})
+		}
+	}
+
+	return r
+}
+
+func (r *Reverb) Set(param string, value float64) error {
+	value = math.Max(0, math.Min(value, 1))
+	switch param {
+	case "size":
+		r.size = value
+	case "damp":
+		r.damp = value
+	case "mix":
+		r.mix = value
+	default:
+		return unknown("reverb", param)
+	}
+	return nil
+}
+
+func (r *Reverb) Process(samples [][2]float64) {
+	feedback := 0.7 + 0.28*r.size
+
+	for i := range samples {
+		// both channels feed the same room
+		in := (samples[i][0] + samples[i][1]) * 0.015
+
+		for c := range samples[i] {
+			var out float64
+			for j := range r.combs[c] {
+				cb := &r.combs[c][j]
+				y := cb.line[cb.pos]
+				cb.store = y*(1-r.damp) + cb.store*r.damp
+				cb.line[cb.pos] = in + cb.store*feedback
+				cb.pos = (cb.pos + 1) % len(cb.line)
+				out += y
+			}
+
+			for j := range r.allpasses[c] {
+				ap := &r.allpasses[c][j]
+				buffered := ap.line[ap.pos]
+				ap.line[ap.pos] = out + buffered*0.5
+				ap.pos = (ap.pos + 1) % len(ap.line)
+				out = buffered - out
+			}
+
+			samples[i][c] += out * r.mix * 3
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package effects
+
+import "math"
+
+// SoftClip rounds off peaks instead of letting the sound card cut them flat, which sounds much harsher
+type SoftClip struct {
+	// drive is how hard the sound is pushed into the curve, louder drive means more distortion
+	drive float64
+}
+
+func NewSoftClip() *SoftClip {
+	return &SoftClip{drive: 1}
+}
+
+func (s *SoftClip) Set(param string, value float64) error {
+	switch param {
+	case "drive":
+		s.drive = math.Max(value, 0.01)
+	default:
+		return unknown("softclip", param)
+	}
+	return nil
+}
+
+func (s *SoftClip) Process(samples [][2]float64) {
+	// scaled so a full scale input stays at full scale
+	scale := 1 / math.Tanh(s.drive)
+	for i := range samples {
+		for c := range samples[i] {
+			samples[i][c] = math.Tanh(samples[i][c]*s.drive) * scale
+		}
+	}
+}
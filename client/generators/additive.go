@@ -0,0 +1,43 @@
+package generators
+
+import (
+	"errors"
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// AdditiveTone returns a constructor for tones made of sine harmonics, like the drawbars of an organ.
+// harmonics[k] is the amplitude of harmonic k+1, the first being the fundamental.
+// Harmonics above the nyquist frequency are left out so the tone never aliases.
+func AdditiveTone(harmonics []float64) func(sr beep.SampleRate, freq float64) (Generator, error) {
+	// scale so the harmonics can never add up past full scale
+	var sum float64
+	for _, a := range harmonics {
+		sum += math.Abs(a)
+	}
+
+	wave := func(t, dt float64) float64 {
+		var v float64
+		for k, a := range harmonics {
+			n := float64(k + 1)
+			if n*dt >= 0.5 {
+				break
+			}
+			v += a * math.Sin(2*math.Pi*n*t)
+		}
+		return v / sum
+	}
+
+	return func(sr beep.SampleRate, freq float64) (Generator, error) {
+		if freq/float64(sr) >= 1.0/2.0 {
+			return nil, errors.New("additive tone generator: samplerate must be at least 2 times grater then frequency")
+		}
+
+		if sum == 0 {
+			return nil, errors.New("additive tone generator: every harmonic is silent")
+		}
+
+		return newTone(sr, freq, wave), nil
+	}
+}
@@ -0,0 +1,146 @@
+package generators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// hit is a single drum hit, it drains once the hit has died away
+type hit struct {
+	sr     beep.SampleRate
+	n, pos int
+	// sample is called with the time since the hit in seconds
+	sample func(t float64) float64
+}
+
+func newHit(sr beep.SampleRate, length float64, sample func(t float64) float64) *hit {
+	return &hit{sr: sr, n: int(length * float64(sr)), sample: sample}
+}
+
+func (h *hit) Stream(samples [][2]float64) (n int, ok bool) {
+	if h.pos >= h.n {
+		return 0, false
+	}
+
+	for n < len(samples) && h.pos < h.n {
+		v := h.sample(float64(h.pos) / float64(h.sr))
+		samples[n][0] = v
+		samples[n][1] = v
+		n++
+		h.pos++
+	}
+
+	return n, true
+}
+
+func (*hit) Err() error {
+	return nil
+}
+
+// highNoise is white noise with the low end taken out, for the sizzle of cymbals and snares
+func highNoise() func() float64 {
+	noise := WhiteNoise(nextSeed())
+	var last float64
+	return func() float64 {
+		n := noise.Sample()
+		v := (n - last) / 2
+		last = n
+		return v
+	}
+}
+
+// sweep is a sine that falls from start to end times freq, the way a drum head drops in pitch after it is hit
+func sweep(sr beep.SampleRate, freq, start, fall float64) func(t float64) float64 {
+	var phase float64
+	return func(t float64) float64 {
+		f := freq * (1 + (start-1)*math.Exp(-t/fall))
+		v := math.Sin(2 * math.Pi * phase)
+		phase += f / float64(sr)
+		return v
+	}
+}
+
+// Kick is a bass drum, a sine falling from 150Hz with a click of noise on top
+func Kick(sr beep.SampleRate) beep.Streamer {
+	body := sweep(sr, 50, 3, 0.03)
+	click := WhiteNoise(nextSeed())
+	return newHit(sr, 0.5, func(t float64) float64 {
+		return 0.8*body(t)*math.Exp(-t/0.15) + 0.2*click.Sample()*math.Exp(-t/0.002)
+	})
+}
+
+// Snare is the ring of a drum head under a burst of bright noise from the snares
+func Snare(sr beep.SampleRate, decay float64) beep.Streamer {
+	body := sweep(sr, 180, 1.5, 0.01)
+	snares := highNoise()
+	return newHit(sr, decay*5, func(t float64) float64 {
+		return 0.4*body(t)*math.Exp(-t/(decay/2)) + 0.6*snares()*math.Exp(-t/decay)
+	})
+}
+
+// Clap is a few bursts of noise close together followed by a short tail
+func Clap(sr beep.SampleRate) beep.Streamer {
+	noise := WhiteNoise(nextSeed())
+	return newHit(sr, 0.4, func(t float64) float64 {
+		var env float64
+		for _, at := range []float64{0, 0.01, 0.02} {
+			if t >= at {
+				env = math.Max(env, math.Exp(-(t-at)/0.004))
+			}
+		}
+		if t >= 0.03 {
+			env = math.Max(env, 0.5*math.Exp(-(t-0.03)/0.08))
+		}
+		return noise.Sample() * env
+	})
+}
+
+// Tom is a drum head tuned to freq
+func Tom(sr beep.SampleRate, freq float64) beep.Streamer {
+	body := sweep(sr, freq, 1.5, 0.05)
+	stick := WhiteNoise(nextSeed())
+	return newHit(sr, 1, func(t float64) float64 {
+		return 0.9*body(t)*math.Exp(-t/0.2) + 0.1*stick.Sample()*math.Exp(-t/0.005)
+	})
+}
+
+// Cymbal is bright noise ringing for about decay seconds, closed hi-hats are very short cymbals
+func Cymbal(sr beep.SampleRate, decay float64) beep.Streamer {
+	sizzle := highNoise()
+	return newHit(sr, decay*6, func(t float64) float64 {
+		return sizzle() * math.Exp(-t/decay)
+	})
+}
+
+// Drum creates the hit for a key on the General MIDI percussion channel
+func Drum(sr beep.SampleRate, key uint8) (beep.Streamer, error) {
+	switch key {
+	case 35, 36: // bass drums
+		return Kick(sr), nil
+	case 37: // side stick
+		return Snare(sr, 0.02), nil
+	case 38, 40: // snares
+		return Snare(sr, 0.12), nil
+	case 39: // hand clap
+		return Clap(sr), nil
+	case 41, 43, 45, 47, 48, 50: // toms from low floor to high
+		toms := map[uint8]float64{41: 80, 43: 95, 45: 110, 47: 130, 48: 150, 50: 175}
+		return Tom(sr, toms[key]), nil
+	case 42: // closed hi-hat
+		return Cymbal(sr, 0.03), nil
+	case 44: // pedal hi-hat
+		return Cymbal(sr, 0.05), nil
+	case 46: // open hi-hat
+		return Cymbal(sr, 0.25), nil
+	case 49, 52, 57: // crash and chinese cymbals
+		return Cymbal(sr, 0.8), nil
+	case 51, 53, 59: // ride cymbals and bell
+		return Cymbal(sr, 0.5), nil
+	case 55: // splash cymbal
+		return Cymbal(sr, 0.3), nil
+	default:
+		return nil, fmt.Errorf("no drum for key %d", key)
+	}
+}
@@ -0,0 +1,47 @@
+package generators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+func TestDrum(t *testing.T) {
+	const sr = beep.SampleRate(48000)
+
+	for _, key := range []uint8{35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51, 52, 53, 55, 57, 59} {
+		d, err := Drum(sr, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// every hit should be audible, stay within full scale and die away within 5 seconds
+		var n int
+		var peak float64
+		buf := make([][2]float64, 512)
+		for {
+			sn, ok := d.Stream(buf)
+			for _, s := range buf[:sn] {
+				peak = math.Max(peak, math.Abs(s[0]))
+			}
+			n += sn
+			if !ok {
+				break
+			}
+
+			if sr.D(n) > 5*time.Second {
+				t.Fatalf("Key %d is still playing after 5 seconds", key)
+			}
+		}
+
+		if peak > 1 || peak < 0.1 {
+			t.Errorf("Key %d peaked at %v", key, peak)
+		}
+	}
+
+	if _, err := Drum(sr, 60); err == nil {
+		t.Error("Expected no drum for key 60")
+	}
+}
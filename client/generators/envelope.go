@@ -0,0 +1,122 @@
+package generators
+
+import (
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// ADSR describes how a note fades in while it is held and fades out once it is let go
+type ADSR struct {
+	Attack  time.Duration // time to rise from silence to full amplitude
+	Decay   time.Duration // time to fall from full amplitude to the sustain level
+	Sustain float64       // level held until the note is released, between 0 and 1
+	Release time.Duration // time to fall to silence after the note is released
+}
+
+// Envelope shapes a streamer with an ADSR envelope.
+// The note is released after the hold time or when Release is called, whichever comes first,
+// and the streamer is drained once the release has finished.
+type Envelope struct {
+	streamer beep.Streamer
+
+	attack, decay, release int
+	sustain                float64
+
+	hold int
+	pos  int
+
+	// set once the note is released
+	released     bool
+	releasePos   int
+	releaseLevel float64
+}
+
+// NewEnvelope wraps the streamer in the envelope for a note held for the given time.
+// The envelope keeps playing for adsr.Release after that, past the nominal end of the note.
+func NewEnvelope(sr beep.SampleRate, s beep.Streamer, adsr ADSR, hold time.Duration) *Envelope {
+	return &Envelope{
+		streamer: s,
+		attack:   sr.N(adsr.Attack),
+		decay:    sr.N(adsr.Decay),
+		sustain:  adsr.Sustain,
+		release:  sr.N(adsr.Release),
+		hold:     sr.N(hold),
+	}
+}
+
+// Release lets go of the note early. Must be called with the speaker locked while it is playing.
+func (e *Envelope) Release() {
+	if !e.released {
+		e.releaseAt(e.pos)
+	}
+}
+
+func (e *Envelope) releaseAt(pos int) {
+	e.releaseLevel = e.held(pos)
+	e.releasePos = pos
+	e.released = true
+}
+
+// held is the level of the envelope at pos while the note is held
+func (e *Envelope) held(pos int) float64 {
+	switch {
+	case pos < e.attack:
+		return float64(pos) / float64(e.attack)
+	case pos < e.attack+e.decay:
+		return 1 - (1-e.sustain)*float64(pos-e.attack)/float64(e.decay)
+	default:
+		return e.sustain
+	}
+}
+
+// level is the level of the envelope at the current position
+func (e *Envelope) level() float64 {
+	if !e.released && e.pos >= e.hold {
+		e.releaseAt(e.hold)
+	}
+
+	if !e.released {
+		return e.held(e.pos)
+	}
+
+	if e.release == 0 {
+		return 0
+	}
+
+	return e.releaseLevel * (1 - float64(e.pos-e.releasePos)/float64(e.release))
+}
+
+// Stream streams the wrapped Streamer multiplied by the envelope
+func (e *Envelope) Stream(samples [][2]float64) (n int, ok bool) {
+	// stop at the end of the release
+	if e.released || e.pos+len(samples) > e.hold {
+		end := e.hold
+		if e.released {
+			end = e.releasePos
+		}
+		end += e.release
+
+		if e.pos >= end {
+			return 0, false
+		}
+
+		if left := end - e.pos; left < len(samples) {
+			samples = samples[:left]
+		}
+	}
+
+	n, ok = e.streamer.Stream(samples)
+	for i := range samples[:n] {
+		l := e.level()
+		samples[i][0] *= l
+		samples[i][1] *= l
+		e.pos++
+	}
+
+	return n, ok
+}
+
+func (e *Envelope) Err() error {
+	return e.streamer.Err()
+}
@@ -0,0 +1,69 @@
+package generators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// constant streams a constant full scale signal
+var constant = beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+	for i := range samples {
+		samples[i] = [2]float64{1, 1}
+	}
+	return len(samples), true
+})
+
+func TestEnvelope(t *testing.T) {
+	sr := beep.SampleRate(1000)
+	adsr := ADSR{
+		Attack:  10 * time.Millisecond,
+		Decay:   10 * time.Millisecond,
+		Sustain: 0.5,
+		Release: 10 * time.Millisecond,
+	}
+
+	e := NewEnvelope(sr, constant, adsr, 50*time.Millisecond)
+
+	// the note plays past its hold time for the length of the release
+	samples := make([][2]float64, 100)
+	n, _ := e.Stream(samples)
+	if n != 60 {
+		t.Fatalf("Expected 60 samples, got %d", n)
+	}
+
+	expected := map[int]float64{0: 0, 5: 0.5, 10: 1, 15: 0.75, 30: 0.5, 55: 0.25}
+	for i, v := range expected {
+		if math.Abs(samples[i][0]-v) > 1e-9 {
+			t.Errorf("Expected level %v at sample %d, got %v", v, i, samples[i][0])
+		}
+	}
+
+	if _, ok := e.Stream(samples); ok {
+		t.Error("Expected the envelope to be drained")
+	}
+}
+
+func TestEnvelopeRelease(t *testing.T) {
+	sr := beep.SampleRate(1000)
+	adsr := ADSR{Attack: 10 * time.Millisecond, Sustain: 1, Release: 10 * time.Millisecond}
+
+	e := NewEnvelope(sr, constant, adsr, time.Minute)
+
+	// release halfway through the attack, it should fade from there instead of jumping
+	samples := make([][2]float64, 5)
+	e.Stream(samples)
+	e.Release()
+
+	samples = make([][2]float64, 100)
+	n, _ := e.Stream(samples)
+	if n != 10 {
+		t.Fatalf("Expected 10 samples of release, got %d", n)
+	}
+
+	if math.Abs(samples[0][0]-0.5) > 1e-9 {
+		t.Errorf("Expected the release to start at 0.5, got %v", samples[0][0])
+	}
+}
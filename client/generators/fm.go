@@ -0,0 +1,64 @@
+package generators
+
+import (
+	"errors"
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// fmGenerator is a two operator FM voice, a sine modulator bending the phase of a sine carrier
+type fmGenerator struct {
+	sr beep.SampleRate
+
+	// carrier phase and increment
+	t, dt float64
+	// modulator phase and increment
+	mt, mdt float64
+
+	ratio, index float64
+}
+
+// FMTone returns a constructor for two operator FM tones.
+// The modulator runs at ratio times the note's frequency, and index sets how far it bends the carrier,
+// whole number ratios sound harmonic while others sound like bells.
+func FMTone(ratio, index float64) func(sr beep.SampleRate, freq float64) (Generator, error) {
+	return func(sr beep.SampleRate, freq float64) (Generator, error) {
+		if freq/float64(sr) >= 1.0/2.0 {
+			return nil, errors.New("fm tone generator: samplerate must be at least 2 times grater then frequency")
+		}
+
+		g := &fmGenerator{sr: sr, ratio: ratio, index: index}
+		g.SetFrequency(freq)
+		return g, nil
+	}
+}
+
+func (g *fmGenerator) SetFrequency(freq float64) {
+	g.dt = freq / float64(g.sr)
+	g.mdt = g.dt * g.ratio
+}
+
+func (g *fmGenerator) Phase() float64 {
+	return g.t
+}
+
+func (g *fmGenerator) SetPhase(t float64) {
+	_, g.t = math.Modf(t)
+}
+
+func (g *fmGenerator) Stream(samples [][2]float64) (n int, ok bool) {
+	for i := range samples {
+		v := math.Sin(2*math.Pi*g.t + g.index*math.Sin(2*math.Pi*g.mt))
+		samples[i][0] = v
+		samples[i][1] = v
+		_, g.t = math.Modf(g.t + g.dt)
+		_, g.mt = math.Modf(g.mt + g.mdt)
+	}
+
+	return len(samples), true
+}
+
+func (*fmGenerator) Err() error {
+	return nil
+}
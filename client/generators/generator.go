@@ -0,0 +1,58 @@
+package generators
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+)
+
+// Generator is an endless tone whose frequency and phase can change while it plays
+type Generator interface {
+	beep.Streamer
+
+	// SetFrequency changes the pitch without resetting the phase
+	SetFrequency(freq float64)
+	// Phase is the position in the current cycle, between 0 and 1
+	Phase() float64
+	SetPhase(t float64)
+}
+
+// tone is a Generator that plays a periodic wave
+type tone struct {
+	sr beep.SampleRate
+	dt float64
+	t  float64
+
+	wave Wave
+}
+
+func newTone(sr beep.SampleRate, freq float64, wave Wave) *tone {
+	return &tone{sr: sr, dt: freq / float64(sr), wave: wave}
+}
+
+func (g *tone) SetFrequency(freq float64) {
+	g.dt = freq / float64(g.sr)
+}
+
+func (g *tone) Phase() float64 {
+	return g.t
+}
+
+func (g *tone) SetPhase(t float64) {
+	_, g.t = math.Modf(t)
+}
+
+func (g *tone) Stream(samples [][2]float64) (n int, ok bool) {
+	for i := range samples {
+		v := g.wave(g.t, g.dt)
+		samples[i][0] = v
+		samples[i][1] = v
+		_, g.t = math.Modf(g.t + g.dt)
+	}
+
+	return len(samples), true
+}
+
+func (*tone) Err() error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package generators
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+type stage int
+
+const (
+	idle stage = iota
+	attack
+	decay
+	sustain
+	release
+)
+
+// Legato is a monophonic voice that plays every note on the same oscillator.
+// A note that starts while the previous one is still sounding glides to its pitch without
+// restarting the phase or the envelope, so a melody sounds like one connected line.
+// Legato never drains, it is silent between notes.
+type Legato struct {
+	sr    beep.SampleRate
+	gen   Generator
+	adsr  ADSR
+	glide time.Duration
+
+	// frequency of the current note
+	freq float64
+	// frequency being played while gliding to freq
+	current float64
+	// multiplier applied to current every sample while gliding
+	ratio float64
+	// samples left in the glide
+	gliding int
+
+	stage stage
+	level float64
+	// samples left until the note is released
+	hold int
+	// how much the level falls every sample of the release
+	releaseStep float64
+
+	// amplitude moves smoothly towards the amplitude of the current note
+	amp, targetAmp float64
+}
+
+// NewLegato plays every note on gen, changing its frequency never resets its phase
+func NewLegato(sr beep.SampleRate, gen Generator, glide time.Duration) *Legato {
+	return &Legato{
+		sr:    sr,
+		gen:   gen,
+		glide: glide,
+	}
+}
+
+// Note plays a note held for the given time, the envelope only comes into play when the
+// note doesn't connect to the previous one. Must be called with the speaker locked.
+func (l *Legato) Note(freq, amplitude float64, hold time.Duration, adsr ADSR) {
+	l.adsr = adsr
+	l.freq = freq
+
+	l.gliding = l.sr.N(l.glide)
+	if l.stage == idle || l.gliding <= 0 || l.current <= 0 {
+		l.current = freq
+		l.gliding = 0
+		l.gen.SetFrequency(freq)
+	} else {
+		// glide exponentially so every step is the same musical interval
+		l.ratio = math.Pow(freq/l.current, 1/float64(l.gliding))
+	}
+
+	if l.stage == idle {
+		l.amp = amplitude
+	}
+
+	// a note that was fading out rises back from where it is
+	if l.stage == idle || l.stage == release {
+		l.stage = attack
+	}
+
+	l.targetAmp = amplitude
+	l.hold = l.sr.N(hold)
+}
+
+// Frequency is the frequency the voice is playing or gliding to
+func (l *Legato) Frequency() float64 {
+	return l.freq
+}
+
+// Release lets go of the current note. Must be called with the speaker locked.
+func (l *Legato) Release() {
+	if l.stage != idle && l.stage != release {
+		l.stage = release
+		l.releaseStep = rate(l.sr, l.adsr.Release, l.level)
+	}
+}
+
+// step advances the envelope by one sample
+func (l *Legato) step() {
+	if l.stage != idle && l.stage != release {
+		l.hold--
+		if l.hold <= 0 {
+			l.Release()
+		}
+	}
+
+	switch l.stage {
+	case attack:
+		l.level += rate(l.sr, l.adsr.Attack, 1)
+		if l.level >= 1 {
+			l.level = 1
+			l.stage = decay
+		}
+	case decay:
+		l.level -= rate(l.sr, l.adsr.Decay, 1-l.adsr.Sustain)
+		if l.level <= l.adsr.Sustain {
+			l.level = l.adsr.Sustain
+			l.stage = sustain
+		}
+	case sustain:
+		l.level = l.adsr.Sustain
+	case release:
+		l.level -= l.releaseStep
+		if l.level <= 0 {
+			l.level = 0
+			l.stage = idle
+		}
+	}
+}
+
+// rate is how much the level changes per sample to cover span in d
+func rate(sr beep.SampleRate, d time.Duration, span float64) float64 {
+	n := sr.N(d)
+	if n <= 0 {
+		return math.Inf(1)
+	}
+	return span / float64(n)
+}
+
+func (l *Legato) Stream(samples [][2]float64) (n int, ok bool) {
+	// while gliding the frequency changes every sample
+	i := 0
+	for ; i < len(samples) && l.gliding > 0; i++ {
+		l.gen.Stream(samples[i : i+1])
+
+		l.current *= l.ratio
+		l.gliding--
+		if l.gliding == 0 {
+			l.current = l.freq
+		}
+		l.gen.SetFrequency(l.current)
+	}
+	l.gen.Stream(samples[i:])
+
+	// about 5ms to follow a change in amplitude
+	smoothing := 1 / float64(l.sr.N(time.Millisecond*5)+1)
+
+	for i := range samples {
+		l.step()
+		l.amp += (l.targetAmp - l.amp) * smoothing
+
+		v := l.level * l.amp
+		samples[i][0] *= v
+		samples[i][1] *= v
+	}
+
+	return len(samples), true
+}
+
+func (l *Legato) Err() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package generators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+func TestLegatoPhase(t *testing.T) {
+	sr := beep.SampleRate(48000)
+	adsr := ADSR{Attack: time.Millisecond, Sustain: 1, Release: time.Millisecond}
+
+	l := NewLegato(sr, newTone(sr, 440, Sine), 0)
+	l.Note(440, 1, time.Second, adsr)
+
+	first := make([][2]float64, 1001)
+	l.Stream(first)
+
+	// the next note starts mid cycle, the wave should carry on from where it was
+	l.Note(660, 1, time.Second, adsr)
+
+	second := make([][2]float64, 1000)
+	l.Stream(second)
+
+	// a sine at 660Hz never moves more than this between two samples
+	limit := 2 * math.Pi * 660 / float64(sr) * 1.01
+
+	prev := first[len(first)-1][0]
+	for i, s := range second {
+		if math.Abs(s[0]-prev) > limit {
+			t.Fatalf("Discontinuity of %v at sample %d", math.Abs(s[0]-prev), i)
+		}
+		prev = s[0]
+	}
+}
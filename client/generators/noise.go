@@ -0,0 +1,118 @@
+package generators
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/faiface/beep"
+)
+
+// seeds hands out the seed of every noise source, so the noise of a whole render can be repeated with SetSeed
+var seeds = struct {
+	sync.Mutex
+	rng *rand.Rand
+}{rng: rand.New(rand.NewSource(1))}
+
+// SetSeed restarts the seeds of the noise sources created from now on
+func SetSeed(seed int64) {
+	seeds.Lock()
+	seeds.rng = rand.New(rand.NewSource(seed))
+	seeds.Unlock()
+}
+
+func nextSeed() int64 {
+	seeds.Lock()
+	defer seeds.Unlock()
+	return seeds.rng.Int63()
+}
+
+// Noise is random noise. It is a Generator so it can be played as a timbre, but it has no pitch.
+type Noise struct {
+	rng *rand.Rand
+	// filter turns white noise into the color of the noise
+	filter func(white float64) float64
+}
+
+func newNoise(seed int64, filter func(white float64) float64) *Noise {
+	return &Noise{rng: rand.New(rand.NewSource(seed)), filter: filter}
+}
+
+// WhiteNoise has the same energy at every frequency, it sounds like hiss
+func WhiteNoise(seed int64) *Noise {
+	return newNoise(seed, func(white float64) float64 {
+		return white
+	})
+}
+
+// PinkNoise has the same energy in every octave, it sounds like rain and is the usual signal for
+// tuning speakers by ear. Uses Paul Kellet's filter.
+func PinkNoise(seed int64) *Noise {
+	var b [7]float64
+	return newNoise(seed, func(white float64) float64 {
+		b[0] = 0.99886*b[0] + white*0.0555179
+		b[1] = 0.99332*b[1] + white*0.0750759
+		b[2] = 0.96900*b[2] + white*0.1538520
+		b[3] = 0.86650*b[3] + white*0.3104856
+		b[4] = 0.55000*b[4] + white*0.5329522
+		b[5] = -0.7616*b[5] - white*0.0168980
+		v := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+		b[6] = white * 0.115926
+		return clip(v * 0.11)
+	})
+}
+
+// BrownNoise falls 6dB every octave, it sounds like a rumble
+func BrownNoise(seed int64) *Noise {
+	var last float64
+	return newNoise(seed, func(white float64) float64 {
+		// leaky integration keeps it from drifting away from zero
+		last = (last + 0.02*white) / 1.02
+		return clip(last * 3.5)
+	})
+}
+
+func clip(v float64) float64 {
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// Sample returns the next sample of the noise
+func (n *Noise) Sample() float64 {
+	return n.filter(n.rng.Float64()*2 - 1)
+}
+
+func (n *Noise) Stream(samples [][2]float64) (int, bool) {
+	for i := range samples {
+		v := n.Sample()
+		samples[i][0] = v
+		samples[i][1] = v
+	}
+
+	return len(samples), true
+}
+
+func (*Noise) Err() error {
+	return nil
+}
+
+// SetFrequency does nothing, noise has no pitch
+func (*Noise) SetFrequency(freq float64) {}
+
+// Phase is always 0, noise has no phase to keep
+func (*Noise) Phase() float64 {
+	return 0
+}
+
+func (*Noise) SetPhase(t float64) {}
+
+// noiseTone adapts a noise constructor to a timbre, every note gets its own seed
+func noiseTone(noise func(seed int64) *Noise) func(sr beep.SampleRate, freq float64) (Generator, error) {
+	return func(sr beep.SampleRate, freq float64) (Generator, error) {
+		return noise(nextSeed()), nil
+	}
+}
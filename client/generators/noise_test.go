@@ -0,0 +1,46 @@
+package generators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	colors := []struct {
+		name  string
+		noise func(seed int64) *Noise
+	}{
+		{"white", WhiteNoise},
+		{"pink", PinkNoise},
+		{"brown", BrownNoise},
+	}
+
+	// brightness is the energy of the changes between samples relative to the energy of the noise,
+	// each color should be darker than the one before
+	last := math.Inf(1)
+	for _, c := range colors {
+		a, b := c.noise(42), c.noise(42)
+
+		var energy, changes, prev float64
+		for i := 0; i < 100000; i++ {
+			v := a.Sample()
+			if v != b.Sample() {
+				t.Fatalf("%s: the same seed gave different noise", c.name)
+			}
+
+			if v > 1 || v < -1 {
+				t.Fatalf("%s: sample %v out of range", c.name, v)
+			}
+
+			energy += v * v
+			changes += (v - prev) * (v - prev)
+			prev = v
+		}
+
+		brightness := changes / energy
+		if brightness >= last {
+			t.Errorf("%s: expected to be darker than the previous color, brightness %v >= %v", c.name, brightness, last)
+		}
+		last = brightness
+	}
+}
@@ -0,0 +1,132 @@
+package generators
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// the lowest note a plucked string can be tuned to, sets the size of the delay line
+const lowestPluck = 20.0
+
+// pluck is a Karplus-Strong plucked string: a burst of noise circulating in a delay line one
+// period long, averaged with its neighbour on every trip so the high harmonics die out first
+type pluck struct {
+	sr beep.SampleRate
+
+	line []float64
+	pos  int
+
+	// length of the loop in samples, fractional so notes stay in tune
+	delay float64
+	// loop gain, sets how long the string rings
+	gain float64
+	ring time.Duration
+
+	// the previous sample read from the line, the loop filter averages it with the next one
+	last float64
+}
+
+// PluckedString returns a constructor for plucked strings like a guitar or a harp.
+// ring is how long the string takes to fall by 60dB, brightness between 0 and 1 is how much of the
+// high end the pluck starts with, low values sound like a soft finger and high values like a pick.
+func PluckedString(ring time.Duration, brightness float64) func(sr beep.SampleRate, freq float64) (Generator, error) {
+	return func(sr beep.SampleRate, freq float64) (Generator, error) {
+		if freq/float64(sr) >= 1.0/2.0 {
+			return nil, errors.New("plucked string generator: samplerate must be at least 2 times grater then frequency")
+		}
+
+		if freq < lowestPluck {
+			return nil, errors.New("plucked string generator: frequency is too low")
+		}
+
+		if ring <= 0 {
+			return nil, errors.New("plucked string generator: ring must be above 0")
+		}
+
+		if brightness <= 0 || brightness > 1 {
+			return nil, errors.New("plucked string generator: brightness must be above 0 and at most 1")
+		}
+
+		p := &pluck{
+			sr:   sr,
+			line: make([]float64, int(float64(sr)/lowestPluck)+2),
+			ring: ring,
+		}
+		p.SetFrequency(freq)
+		p.excite(brightness)
+
+		return p, nil
+	}
+}
+
+// excite fills the delay line with low passed noise, without DC so the string settles at zero
+func (p *pluck) excite(brightness float64) {
+	noise := WhiteNoise(nextSeed())
+	var v, mean float64
+	for i := range p.line {
+		v += brightness * (noise.Sample() - v)
+		p.line[i] = v
+		mean += v
+	}
+	mean /= float64(len(p.line))
+
+	var peak float64
+	for i := range p.line {
+		p.line[i] -= mean
+		peak = math.Max(peak, math.Abs(p.line[i]))
+	}
+
+	for i := range p.line {
+		p.line[i] /= peak
+	}
+}
+
+// SetFrequency retunes the string, the loop filter delays by half a sample so it is taken off the line
+func (p *pluck) SetFrequency(freq float64) {
+	p.delay = float64(p.sr)/freq - 0.5
+	if max := float64(len(p.line) - 2); p.delay > max {
+		p.delay = max
+	}
+
+	// the signal goes around the loop freq times a second
+	p.gain = math.Pow(10, -3/(freq*p.ring.Seconds()))
+}
+
+// Phase is always 0, a plucked string has no phase to keep
+func (p *pluck) Phase() float64 {
+	return 0
+}
+
+func (p *pluck) SetPhase(t float64) {}
+
+// read reads the line delay samples ago, linearly interpolated
+func (p *pluck) read() float64 {
+	i, frac := math.Modf(p.delay)
+	n := len(p.line)
+	a := p.line[(p.pos-int(i)+n)%n]
+	b := p.line[(p.pos-int(i)-1+n)%n]
+	return a + (b-a)*frac
+}
+
+func (p *pluck) Stream(samples [][2]float64) (n int, ok bool) {
+	for i := range samples {
+		r := p.read()
+		v := p.gain * (r + p.last) / 2
+		p.last = r
+
+		p.line[p.pos] = v
+		p.pos = (p.pos + 1) % len(p.line)
+
+		samples[i][0] = v
+		samples[i][1] = v
+	}
+
+	return len(samples), true
+}
+
+func (*pluck) Err() error {
+	return nil
+}
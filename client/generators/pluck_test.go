@@ -0,0 +1,60 @@
+package generators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+func TestPluckedString(t *testing.T) {
+	const sr = beep.SampleRate(48000)
+	const freq = 329.63
+	const ring = time.Second
+
+	// the pluck starts with noise, whose high end decides how loud the start is
+	SetSeed(1)
+
+	g, err := PluckedString(ring, 0.5)(sr, freq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([][2]float64, sr.N(ring))
+	g.Stream(samples)
+
+	// the string should be in tune, find the period by autocorrelation a little after the pluck
+	x := samples[sr.N(100*time.Millisecond):]
+	best, bestLag := math.Inf(-1), 0
+	for lag := 100; lag < 300; lag++ {
+		var c float64
+		for i := 0; i < 4096; i++ {
+			c += x[i][0] * x[i+lag][0]
+		}
+		if c > best {
+			best, bestLag = c, lag
+		}
+	}
+
+	period := float64(sr) / freq
+	if math.Abs(float64(bestLag)-period) > 1 {
+		t.Errorf("Expected a period of %.1f samples, got %d", period, bestLag)
+	}
+
+	// the fundamental should fall by 60dB over the ring time, skip the start where the high
+	// harmonics die out faster
+	rms := func(s [][2]float64) float64 {
+		var sum float64
+		for _, v := range s {
+			sum += v[0] * v[0]
+		}
+		return math.Sqrt(sum / float64(len(s)))
+	}
+
+	start := rms(x[:1000])
+	end := rms(samples[len(samples)-1000:])
+	if db := 20 * math.Log10(end/start); db > -48 || db < -60 {
+		t.Errorf("Expected the string to fall by about 54dB in %v, it fell by %.1fdB", ring-100*time.Millisecond, -db)
+	}
+}
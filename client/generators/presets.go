@@ -0,0 +1,191 @@
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Preset describes a timbre in a presets file, for example
+//
+//	{"timbres": [
+//		{"name": "organ", "type": "additive", "harmonics": [1, 0.8, 0, 0.5], "attack": "5ms", "sustain": 1, "release": "60ms"},
+//		{"name": "bell", "type": "fm", "ratio": 3.5, "index": 2.5, "decay": "1.5s", "sustain": 0, "release": "800ms"},
+//		{"name": "harp", "type": "pluck", "ring": "3s", "brightness": 0.4, "sustain": 1},
+//		{"name": "piano", "type": "sampler", "samples": [{"file": "piano-c4.wav", "root": 60}, {"file": "piano-c5.wav", "root": 72}]},
+//		{"name": "soft-sine", "type": "sine", "attack": "80ms", "effects": "reverb:mix=0.4"}
+//	]}
+//
+// A preset whose type is the name of another timbre copies it, which is handy to change just its envelope.
+type Preset struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// additive
+	Harmonics []float64 `json:"harmonics"`
+
+	// fm
+	Ratio float64 `json:"ratio"`
+	Index float64 `json:"index"`
+
+	// pluck, ring is a go duration
+	Ring       string  `json:"ring"`
+	Brightness float64 `json:"brightness"`
+
+	// sampler, files are relative to the presets file
+	Samples []struct {
+		File      string `json:"file"`
+		Root      int    `json:"root"`
+		LoopStart int    `json:"loop_start"`
+		LoopEnd   int    `json:"loop_end"`
+	} `json:"samples"`
+	dir string
+
+	// envelope, times are go durations like "150ms". Anything left out keeps the
+	// envelope of the copied timbre, or defaultEnvelope
+	Attack  string   `json:"attack"`
+	Decay   string   `json:"decay"`
+	Sustain *float64 `json:"sustain"`
+	Release string   `json:"release"`
+
+	// effects chain like "lowpass:cutoff=2000;reverb:mix=0.3", replaces the one of a copied timbre
+	Effects string `json:"effects"`
+}
+
+var defaultEnvelope = ADSR{Attack: 10 * time.Millisecond, Decay: 100 * time.Millisecond, Sustain: 0.8, Release: 100 * time.Millisecond}
+
+// builders create the generator constructor of a preset type
+var builders = map[string]func(p Preset) (func(sr beep.SampleRate, freq float64) (Generator, error), error){
+	"additive": func(p Preset) (func(sr beep.SampleRate, freq float64) (Generator, error), error) {
+		if len(p.Harmonics) == 0 {
+			return nil, fmt.Errorf("preset %q: additive needs harmonics", p.Name)
+		}
+		return AdditiveTone(p.Harmonics), nil
+	},
+	"fm": func(p Preset) (func(sr beep.SampleRate, freq float64) (Generator, error), error) {
+		if p.Ratio <= 0 {
+			return nil, fmt.Errorf("preset %q: fm needs a ratio above 0", p.Name)
+		}
+		return FMTone(p.Ratio, p.Index), nil
+	},
+	"pluck": func(p Preset) (func(sr beep.SampleRate, freq float64) (Generator, error), error) {
+		ring, err := time.ParseDuration(p.Ring)
+		if err != nil || ring <= 0 {
+			return nil, fmt.Errorf("preset %q: pluck needs a ring time above 0", p.Name)
+		}
+		if p.Brightness <= 0 || p.Brightness > 1 {
+			return nil, fmt.Errorf("preset %q: pluck brightness must be above 0 and at most 1", p.Name)
+		}
+		return PluckedString(ring, p.Brightness), nil
+	},
+	"sampler": func(p Preset) (func(sr beep.SampleRate, freq float64) (Generator, error), error) {
+		if len(p.Samples) == 0 {
+			return nil, fmt.Errorf("preset %q: sampler needs samples", p.Name)
+		}
+
+		samples := make([]*Sample, len(p.Samples))
+		for i, s := range p.Samples {
+			filename := s.File
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(p.dir, filename)
+			}
+
+			var err error
+			samples[i], err = LoadSample(filename, s.Root, s.LoopStart, s.LoopEnd)
+			if err != nil {
+				return nil, fmt.Errorf("preset %q: %w", p.Name, err)
+			}
+		}
+		return Sampler(samples), nil
+	},
+}
+
+// LoadPresets registers every timbre in the presets file
+func LoadPresets(filename string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var file struct {
+		Timbres []Preset `json:"timbres"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for _, p := range file.Timbres {
+		p.dir = filepath.Dir(filename)
+		t, err := p.Timbre()
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		Register(t)
+	}
+
+	return nil
+}
+
+// Timbre builds the timbre the preset describes
+func (p Preset) Timbre() (Timbre, error) {
+	t := Timbre{Name: p.Name, Envelope: defaultEnvelope}
+	if p.Name == "" {
+		return t, fmt.Errorf("preset of type %q has no name", p.Type)
+	}
+
+	if _, ok := byName(p.Name); ok {
+		return t, fmt.Errorf("preset %q: a timbre with that name already exists", p.Name)
+	}
+
+	if build, ok := builders[p.Type]; ok {
+		var err error
+		t.New, err = build(p)
+		if err != nil {
+			return t, err
+		}
+	} else if base, ok := byName(p.Type); ok {
+		t.New = base.New
+		t.Envelope = base.Envelope
+		t.Effects = base.Effects
+	} else {
+		return t, fmt.Errorf("preset %q: unknown type %q", p.Name, p.Type)
+	}
+
+	var err error
+	times := []struct {
+		s string
+		d *time.Duration
+	}{
+		{p.Attack, &t.Envelope.Attack},
+		{p.Decay, &t.Envelope.Decay},
+		{p.Release, &t.Envelope.Release},
+	}
+	for _, x := range times {
+		if x.s == "" {
+			continue
+		}
+
+		*x.d, err = time.ParseDuration(x.s)
+		if err != nil {
+			return t, fmt.Errorf("preset %q: %w", p.Name, err)
+		}
+	}
+
+	if p.Effects != "" {
+		t.Effects = p.Effects
+	}
+
+	if p.Sustain != nil {
+		if *p.Sustain < 0 || *p.Sustain > 1 {
+			return t, fmt.Errorf("preset %q: sustain must be between 0 and 1", p.Name)
+		}
+		t.Envelope.Sustain = *p.Sustain
+	}
+
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package generators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+func TestLoadPresets(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "presets.json")
+	err := os.WriteFile(filename, []byte(`{"timbres": [
+		{"name": "test-organ", "type": "additive", "harmonics": [1, 0.5], "attack": "5ms", "sustain": 1},
+		{"name": "test-bell", "type": "fm", "ratio": 3.5, "index": 2, "sustain": 0},
+		{"name": "test-sine", "type": "sine", "release": "1s"}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(n int) { timbres = timbres[:n] }(len(timbres))
+
+	if err := LoadPresets(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	sr := beep.SampleRate(48000)
+	for _, name := range []string{"test-organ", "test-bell", "test-sine"} {
+		timbre, ok := byName(name)
+		if !ok {
+			t.Fatalf("Expected timbre %q to be registered", name)
+		}
+
+		g, err := timbre.New(sr, 440)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// every generator should stay within full scale
+		samples := make([][2]float64, 4800)
+		g.Stream(samples)
+		for _, s := range samples {
+			if s[0] > 1 || s[0] < -1 {
+				t.Fatalf("%s: sample %v out of range", name, s[0])
+			}
+		}
+	}
+
+	// copies keep the envelope of their base and only change what is given
+	sine, _ := byName("sine")
+	copied, _ := byName("test-sine")
+	if copied.Envelope.Attack != sine.Envelope.Attack || copied.Envelope.Release != time.Second {
+		t.Errorf("Expected the sine envelope with a 1s release, got %+v", copied.Envelope)
+	}
+
+	// names must be unique
+	if err := LoadPresets(filename); err == nil {
+		t.Error("Expected loading the same presets twice to fail")
+	}
+}
@@ -0,0 +1,94 @@
+package generators
+
+import (
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Timbre is a named sound the client can play
+type Timbre struct {
+	Name string
+	// Envelope is used unless the PLAY packet brings its own
+	Envelope ADSR
+	// New creates a generator for a note at freq
+	New func(sr beep.SampleRate, freq float64) (Generator, error)
+	// Effects is the effects chain every note of the timbre goes through, see the effects package
+	Effects string
+}
+
+// timbres are numbered in the order they are registered, the number is the voice id in PLAY packets
+var timbres []Timbre
+
+// Register adds a timbre and returns its voice id
+func Register(t Timbre) uint32 {
+	timbres = append(timbres, t)
+	return uint32(len(timbres) - 1)
+}
+
+// Lookup finds the timbre of a voice id
+func Lookup(voice uint32) (Timbre, bool) {
+	if int(voice) >= len(timbres) {
+		return Timbre{}, false
+	}
+	return timbres[voice], true
+}
+
+// byName finds a timbre by its name
+func byName(name string) (Timbre, bool) {
+	for _, t := range timbres {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Timbre{}, false
+}
+
+// Timbres lists every registered timbre, indexed by voice id
+func Timbres() []Timbre {
+	return timbres
+}
+
+// the first four voice ids are part of the protocol, keep them in this order
+func init() {
+	Register(Timbre{
+		Name:     "sine",
+		Envelope: ADSR{Attack: 10 * time.Millisecond, Decay: 200 * time.Millisecond, Sustain: 0.8, Release: 150 * time.Millisecond},
+		New:      SineTone,
+	})
+	Register(Timbre{
+		Name:     "sawtooth",
+		Envelope: ADSR{Attack: 5 * time.Millisecond, Decay: 100 * time.Millisecond, Sustain: 0.6, Release: 80 * time.Millisecond},
+		New:      SawtoothTone,
+	})
+	Register(Timbre{
+		Name:     "square",
+		Envelope: ADSR{Attack: 5 * time.Millisecond, Decay: 80 * time.Millisecond, Sustain: 0.5, Release: 60 * time.Millisecond},
+		New:      SquareTone,
+	})
+	Register(Timbre{
+		Name:     "triangle",
+		Envelope: ADSR{Attack: 8 * time.Millisecond, Decay: 150 * time.Millisecond, Sustain: 0.7, Release: 120 * time.Millisecond},
+		New:      TriangleTone,
+	})
+	Register(Timbre{
+		Name:     "pluck",
+		Envelope: ADSR{Attack: time.Millisecond, Decay: 0, Sustain: 1, Release: 100 * time.Millisecond},
+		New:      PluckedString(2*time.Second, 0.6),
+	})
+	Register(Timbre{
+		Name:     "white",
+		Envelope: ADSR{Attack: 5 * time.Millisecond, Decay: 0, Sustain: 1, Release: 50 * time.Millisecond},
+		New:      noiseTone(WhiteNoise),
+	})
+	Register(Timbre{
+		Name:     "pink",
+		Envelope: ADSR{Attack: 5 * time.Millisecond, Decay: 0, Sustain: 1, Release: 50 * time.Millisecond},
+		New:      noiseTone(PinkNoise),
+	})
+	Register(Timbre{
+		Name:     "brown",
+		Envelope: ADSR{Attack: 5 * time.Millisecond, Decay: 0, Sustain: 1, Release: 50 * time.Millisecond},
+		New:      noiseTone(BrownNoise),
+	})
+}
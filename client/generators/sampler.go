@@ -0,0 +1,152 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+)
+
+// Sample is a recorded note
+type Sample struct {
+	Data       [][2]float64
+	SampleRate beep.SampleRate
+	// Root is the frequency the note was recorded at
+	Root float64
+	// the sample repeats from LoopStart to LoopEnd while the note is held, without a loop
+	// it plays once and then stays silent
+	LoopStart, LoopEnd int
+}
+
+// LoadSample reads a WAV file recorded at the midi key root.
+// loopEnd 0 means the sample doesn't loop.
+func LoadSample(filename string, root int, loopStart, loopEnd int) (*Sample, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s, format, err := wav.Decode(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	defer s.Close()
+
+	data := make([][2]float64, s.Len())
+	n := 0
+	for n < len(data) {
+		sn, ok := s.Stream(data[n:])
+		n += sn
+		if !ok {
+			break
+		}
+	}
+	if s.Err() != nil {
+		return nil, fmt.Errorf("%s: %w", filename, s.Err())
+	}
+
+	sample := &Sample{
+		Data:       data[:n],
+		SampleRate: format.SampleRate,
+		Root:       440 * math.Pow(2, float64(root-69)/12),
+		LoopStart:  loopStart,
+		LoopEnd:    loopEnd,
+	}
+
+	if loopEnd != 0 && (loopStart < 0 || loopStart >= loopEnd || loopEnd > n) {
+		return nil, fmt.Errorf("%s: loop %d-%d is outside the %d samples of the file", filename, loopStart, loopEnd, n)
+	}
+
+	return sample, nil
+}
+
+// Sampler returns a constructor that plays each note with the sample whose root is closest to it,
+// pitch shifted by resampling
+func Sampler(samples []*Sample) func(sr beep.SampleRate, freq float64) (Generator, error) {
+	return func(sr beep.SampleRate, freq float64) (Generator, error) {
+		if len(samples) == 0 {
+			return nil, errors.New("sampler: no samples")
+		}
+
+		if freq <= 0 {
+			return nil, errors.New("sampler: frequency must be above 0")
+		}
+
+		// closest in musical interval
+		best := samples[0]
+		for _, s := range samples[1:] {
+			if math.Abs(math.Log(freq/s.Root)) < math.Abs(math.Log(freq/best.Root)) {
+				best = s
+			}
+		}
+
+		g := &sampler{sr: sr, sample: best}
+		g.resampler = beep.ResampleRatio(4, g.ratio(freq), &g.player)
+		g.player.sample = best
+		return g, nil
+	}
+}
+
+// sampler plays a sample through a resampler whose ratio sets the pitch
+type sampler struct {
+	sr        beep.SampleRate
+	sample    *Sample
+	player    samplePlayer
+	resampler *beep.Resampler
+}
+
+// ratio is how many samples of the recording make up a sample of output at freq
+func (g *sampler) ratio(freq float64) float64 {
+	return freq / g.sample.Root * float64(g.sample.SampleRate) / float64(g.sr)
+}
+
+func (g *sampler) SetFrequency(freq float64) {
+	g.resampler.SetRatio(g.ratio(freq))
+}
+
+// Phase is always 0, a sample has no phase to keep
+func (g *sampler) Phase() float64 {
+	return 0
+}
+
+func (g *sampler) SetPhase(t float64) {}
+
+func (g *sampler) Stream(samples [][2]float64) (n int, ok bool) {
+	return g.resampler.Stream(samples)
+}
+
+func (g *sampler) Err() error {
+	return nil
+}
+
+// samplePlayer streams a sample at its own rate, looping if it has a loop, and silence after it ends
+type samplePlayer struct {
+	sample *Sample
+	pos    int
+}
+
+func (p *samplePlayer) Stream(samples [][2]float64) (n int, ok bool) {
+	s := p.sample
+	for i := range samples {
+		if s.LoopEnd != 0 && p.pos >= s.LoopEnd {
+			p.pos = s.LoopStart
+		}
+
+		if p.pos < len(s.Data) {
+			samples[i] = s.Data[p.pos]
+			p.pos++
+		} else {
+			samples[i] = [2]float64{}
+		}
+	}
+
+	return len(samples), true
+}
+
+func (p *samplePlayer) Err() error {
+	return nil
+}
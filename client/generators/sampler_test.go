@@ -0,0 +1,71 @@
+package generators
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+)
+
+func TestSampler(t *testing.T) {
+	// one second of A4 recorded at 44.1kHz, looping over whole cycles
+	const rate = beep.SampleRate(44100)
+	data := make([][2]float64, rate.N(1e9))
+	for i := range data {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+		data[i] = [2]float64{v, v}
+	}
+
+	filename := filepath.Join(t.TempDir(), "a4.wav")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := beep.Format{SampleRate: rate, NumChannels: 2, Precision: 2}
+	if err := wav.Encode(f, sliceStreamer(data), format); err != nil {
+		t.Fatal(err)
+	}
+
+	// 441 samples is exactly 4 cycles
+	sample, err := LoadSample(filename, 69, 0, 44100-441*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const sr = beep.SampleRate(48000)
+	for _, freq := range []float64{220, 440, 659.25} {
+		g, err := Sampler([]*Sample{sample})(sr, freq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// play past the end of the file so the loop is heard too
+		out := make([][2]float64, sr.N(2e9))
+		g.Stream(out)
+
+		// count the cycles by rising zero crossings
+		crossings := 0
+		for i := 1; i < len(out); i++ {
+			if out[i-1][0] < 0 && out[i][0] >= 0 {
+				crossings++
+			}
+		}
+
+		if got := float64(crossings) / 2; math.Abs(got-freq) > 2 {
+			t.Errorf("Expected %vHz, got %vHz", freq, got)
+		}
+	}
+}
+
+func sliceStreamer(data [][2]float64) beep.Streamer {
+	pos := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		n = copy(samples, data[pos:])
+		pos += n
+		return n, n > 0
+	})
+}
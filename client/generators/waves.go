@@ -0,0 +1,90 @@
+package generators
+
+import "math"
+
+// Wave gives the value of one cycle of a waveform at phase t, where 0 <= t < 1.
+// dt is the phase increment per sample, band-limited waves use it to smooth their edges.
+type Wave func(t, dt float64) float64
+
+// BandLimited makes the tone constructors use the band-limited waves, which alias far less
+// on high notes than the naive ones
+var BandLimited = false
+
+// choose picks the band-limited wave when BandLimited is set
+func choose(naive, bandLimited Wave) Wave {
+	if BandLimited {
+		return bandLimited
+	}
+	return naive
+}
+
+func Sine(t, _ float64) float64 {
+	return math.Sin(t * 2.0 * math.Pi)
+}
+
+func Sawtooth(t, _ float64) float64 {
+	return 2.0*t - 1.0
+}
+
+func Square(t, _ float64) float64 {
+	if t < 0.5 {
+		return 1.0
+	}
+	return -1.0
+}
+
+func Triangle(t, _ float64) float64 {
+	if t < 0.5 {
+		return 2.0*(1-t) - 1
+	}
+	return 2.0*t - 1.0
+}
+
+// SawtoothBL is a sawtooth with its drop smoothed by a PolyBLEP
+func SawtoothBL(t, dt float64) float64 {
+	return Sawtooth(t, dt) - polyBLEP(t, dt)
+}
+
+// SquareBL is a square with both of its edges smoothed by PolyBLEPs
+func SquareBL(t, dt float64) float64 {
+	_, half := math.Modf(t + 0.5)
+	return Square(t, dt) + polyBLEP(t, dt) - polyBLEP(half, dt)
+}
+
+// TriangleBL is a triangle with both of its corners rounded by PolyBLAMPs
+func TriangleBL(t, dt float64) float64 {
+	_, half := math.Modf(t + 0.5)
+
+	// the slope changes by 4 at each corner, down at 0 and up at 0.5.
+	// polyBLAMP is scaled for a change of 2, like polyBLEP is scaled for a step of 2
+	return Triangle(t, dt) + 2*dt*(polyBLAMP(half, dt)-polyBLAMP(t, dt))
+}
+
+// polyBLEP is the difference between a band-limited step of 2 at t = 0 and a naive one,
+// spread over the sample on either side of the step
+func polyBLEP(t, dt float64) float64 {
+	switch {
+	case t < dt:
+		t /= dt
+		return t + t - t*t - 1
+	case t > 1-dt:
+		t = (t - 1) / dt
+		return t*t + t + t + 1
+	default:
+		return 0
+	}
+}
+
+// polyBLAMP is the integral of polyBLEP, it rounds off a corner instead of a step
+func polyBLAMP(t, dt float64) float64 {
+	switch {
+	case t < dt:
+		t = t/dt - 1
+		return -t * t * t / 3
+	case t > 1-dt:
+		t = (t-1)/dt + 1
+		return t * t * t / 3
+	default:
+		return 0
+	}
+}
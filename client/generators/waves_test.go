@@ -0,0 +1,72 @@
+package generators
+
+import (
+	"math"
+	"testing"
+)
+
+// aliasing measures the fraction of the energy of a wave that isn't in its harmonics.
+// Harmonics above the nyquist frequency fold back to frequencies between the real harmonics,
+// so everything away from the harmonics is aliasing.
+func aliasing(wave Wave, freq, sr float64) float64 {
+	const n = 4096
+	dt := freq / sr
+
+	// blackman-harris window keeps the leakage of each harmonic within a few bins
+	samples := make([]float64, n)
+	t := 0.0
+	for i := range samples {
+		x := 2 * math.Pi * float64(i) / (n - 1)
+		w := 0.35875 - 0.48829*math.Cos(x) + 0.14128*math.Cos(2*x) - 0.01168*math.Cos(3*x)
+		samples[i] = wave(t, dt) * w
+		_, t = math.Modf(t + dt)
+	}
+
+	var total, alias float64
+	for bin := 1; bin < n/2; bin++ {
+		var re, im float64
+		for i, v := range samples {
+			phase := 2 * math.Pi * float64(bin) * float64(i) / n
+			re += v * math.Cos(phase)
+			im -= v * math.Sin(phase)
+		}
+		energy := re*re + im*im
+		total += energy
+
+		// distance to the nearest harmonic in bins, DC counts as the zeroth harmonic
+		hz := float64(bin) * sr / n
+		harmonic := math.Round(hz/freq) * freq
+		if math.Abs(hz-harmonic)*n/sr > 4 {
+			alias += energy
+		}
+	}
+
+	return alias / total
+}
+
+func TestBandLimitedAliasing(t *testing.T) {
+	// a high note where the naive waves alias badly
+	const freq, sr = 3951.07, 48000
+
+	// band-limiting should remove at least this much of the aliasing
+	const improvement = 10
+
+	waves := []struct {
+		name      string
+		naive, bl Wave
+	}{
+		{"sawtooth", Sawtooth, SawtoothBL},
+		{"square", Square, SquareBL},
+		{"triangle", Triangle, TriangleBL},
+	}
+
+	for _, w := range waves {
+		naive := aliasing(w.naive, freq, sr)
+		bl := aliasing(w.bl, freq, sr)
+		t.Logf("%s: naive %.5f band-limited %.5f", w.name, naive, bl)
+
+		if bl*improvement > naive {
+			t.Errorf("%s: expected at least %vx less aliasing, naive %.5f band-limited %.5f", w.name, improvement, naive, bl)
+		}
+	}
+}
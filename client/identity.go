@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// defaultStatePath is where the identity is kept between runs
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+
+	return filepath.Join(dir, "itl-chorus", "identity")
+}
+
+// loadIdentity returns the identity this machine used last time, so the server can recognize it.
+// If name is given the identity is derived from it instead, and the state file is updated.
+// On the first run a random identity is created and saved.
+func loadIdentity(path, name string) ([24]byte, error) {
+	var id [24]byte
+
+	if name == "" {
+		b, err := os.ReadFile(path)
+		if err == nil {
+			n, err := hex.Decode(id[:], []byte(strings.TrimSpace(string(b))))
+			if err != nil || n != len(id) {
+				return id, fmt.Errorf("%s: invalid identity", path)
+			}
+
+			return id, nil
+		}
+
+		if !os.IsNotExist(err) {
+			return id, err
+		}
+
+		// Choose a random 24 byte identifier
+		_, err = rand.Read(id[:])
+		if err != nil {
+			return id, err
+		}
+	} else {
+		var err error
+		id, err = shared.NameIdentity(name)
+		if err != nil {
+			return id, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return id, err
+	}
+
+	return id, os.WriteFile(path, []byte(hex.EncodeToString(id[:])+"\n"), 0644)
+}
@@ -0,0 +1,131 @@
+package loopback
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+)
+
+// How long the click lasts, long enough to be found under room noise
+const clickLength = time.Millisecond * 20
+
+// Click creates a windowed chirp sweeping from 1kHz to 4kHz.
+// Unlike a pure tone a chirp only lines up with itself at a single offset, so it is easy to find in a recording.
+func Click(sr beep.SampleRate) []float64 {
+	n := sr.N(clickLength)
+	click := make([]float64, n)
+
+	const f0, f1 = 1000.0, 4000.0
+	for i := range click {
+		t := float64(i) / float64(sr)
+		frac := float64(i) / float64(n)
+
+		// instantaneous frequency rises linearly from f0 to f1
+		phase := 2 * math.Pi * (f0*t + (f1-f0)*t*frac/2)
+
+		// hann window so the click starts and ends at zero
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*frac)
+
+		click[i] = math.Sin(phase) * window
+	}
+
+	return click
+}
+
+// Streamer plays the samples once on both channels
+func Streamer(samples []float64) beep.Streamer {
+	pos := 0
+	return beep.StreamerFunc(func(out [][2]float64) (n int, ok bool) {
+		if pos >= len(samples) {
+			return 0, false
+		}
+
+		for n < len(out) && pos < len(samples) {
+			out[n][0] = samples[pos]
+			out[n][1] = samples[pos]
+			n++
+			pos++
+		}
+
+		return n, true
+	})
+}
+
+// Detect finds where the click starts in the recording using cross-correlation.
+// Returns an error when no offset stands out clearly from the rest of the recording.
+func Detect(recording, click []float64) (int, error) {
+	if len(recording) < len(click) {
+		return 0, errors.New("loopback: recording is shorter than the click")
+	}
+
+	corr := make([]float64, len(recording)-len(click)+1)
+	best := 0
+	var sum float64
+	for i := range corr {
+		var c float64
+		for j, v := range click {
+			c += recording[i+j] * v
+		}
+
+		corr[i] = math.Abs(c)
+		sum += corr[i] * corr[i]
+
+		if corr[i] > corr[best] {
+			best = i
+		}
+	}
+
+	// the peak must be well above the typical correlation
+	rms := math.Sqrt(sum / float64(len(corr)))
+	if corr[best] < 8*rms {
+		return 0, fmt.Errorf("loopback: click not found (peak %.2f, rms %.2f)", corr[best], rms)
+	}
+
+	return best, nil
+}
+
+// Measure finds the click in a recording and returns how long after playedAt it was heard.
+// playedAt is measured from the first sample of the recording.
+func Measure(recording []float64, sr beep.SampleRate, click []float64, playedAt time.Duration) (time.Duration, error) {
+	i, err := Detect(recording, click)
+	if err != nil {
+		return 0, err
+	}
+
+	return sr.D(i) - playedAt, nil
+}
+
+// ReadWAV reads a WAV file as mono samples
+func ReadWAV(filename string) ([]float64, beep.SampleRate, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s, format, err := wav.Decode(f)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	defer s.Close()
+
+	samples := make([]float64, 0, s.Len())
+	buf := make([][2]float64, 512)
+	for {
+		n, ok := s.Stream(buf)
+		for _, v := range buf[:n] {
+			samples = append(samples, (v[0]+v[1])/2)
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	return samples, format.SampleRate, s.Err()
+}
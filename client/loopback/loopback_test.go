@@ -0,0 +1,78 @@
+package loopback
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+)
+
+func TestMeasure(t *testing.T) {
+	// Simulate a recording of a noisy room where the click was played after 500ms and heard 137ms later
+	sr := beep.SampleRate(48000)
+	playedAt := time.Millisecond * 500
+	latency := time.Millisecond * 137
+
+	click := Click(sr)
+	recording := make([]float64, sr.N(time.Second*2))
+
+	rng := rand.New(rand.NewSource(1))
+	for i := range recording {
+		recording[i] = (rng.Float64()*2 - 1) * 0.05
+	}
+
+	at := sr.N(playedAt + latency)
+	for i, v := range click {
+		recording[at+i] += v * 0.3
+	}
+
+	// Round trip it through a WAV file like a real capture
+	filename := filepath.Join(t.TempDir(), "capture.wav")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wav.Encode(f, Streamer(recording), beep.Format{SampleRate: sr, NumChannels: 1, Precision: 2})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &WAVRecorder{Filename: filename, SampleRate: sr}
+	if _, err := rec.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := rec.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	measured, err := Measure(samples, sr, click, playedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := measured - latency; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("Expected latency %v, got %v", latency, measured)
+	}
+}
+
+func TestDetectSilence(t *testing.T) {
+	sr := beep.SampleRate(48000)
+	recording := make([]float64, sr.N(time.Second/2))
+
+	rng := rand.New(rand.NewSource(2))
+	for i := range recording {
+		recording[i] = (rng.Float64()*2 - 1) * 0.05
+	}
+
+	if _, err := Detect(recording, Click(sr)); err == nil {
+		t.Error("Expected no click to be found in noise")
+	}
+}
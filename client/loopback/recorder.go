@@ -0,0 +1,111 @@
+package loopback
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// Recorder captures what the speakers played
+type Recorder interface {
+	// Start begins recording and returns the moment the first sample was captured
+	Start() (time.Time, error)
+	// Stop ends the recording and returns every captured sample in mono
+	Stop() ([]float64, error)
+}
+
+// WAVRecorder replays a capture made earlier, as if it started recording when Start was called
+type WAVRecorder struct {
+	Filename   string
+	SampleRate beep.SampleRate
+}
+
+func (r *WAVRecorder) Start() (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (r *WAVRecorder) Stop() ([]float64, error) {
+	samples, sr, err := ReadWAV(r.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if sr != r.SampleRate {
+		return nil, fmt.Errorf("%s: sample rate %d, expected %d", r.Filename, sr, r.SampleRate)
+	}
+
+	return samples, nil
+}
+
+// ArecordRecorder records the default ALSA input with the arecord command
+type ArecordRecorder struct {
+	SampleRate beep.SampleRate
+
+	cmd     *exec.Cmd
+	wg      sync.WaitGroup
+	samples []float64
+	err     error
+}
+
+func (r *ArecordRecorder) Start() (time.Time, error) {
+	r.cmd = exec.Command("arecord", "-q", "-t", "raw", "-f", "S16_LE", "-c", "1", "-r", strconv.Itoa(int(r.SampleRate)))
+
+	stdout, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := r.cmd.Start(); err != nil {
+		return time.Time{}, err
+	}
+
+	// the first sample was captured about one read earlier than it arrives
+	first := make(chan time.Time, 1)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		rd := bufio.NewReader(stdout)
+		var v int16
+		for {
+			err := binary.Read(rd, binary.LittleEndian, &v)
+			if err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					r.err = err
+				}
+				close(first)
+				return
+			}
+
+			if len(r.samples) == 0 {
+				first <- time.Now().Add(-r.SampleRate.D(rd.Buffered() / 2))
+			}
+
+			r.samples = append(r.samples, float64(v)/32768)
+		}
+	}()
+
+	t, ok := <-first
+	if !ok {
+		r.wg.Wait()
+		return time.Time{}, fmt.Errorf("arecord: no audio captured: %v", r.err)
+	}
+
+	return t, nil
+}
+
+func (r *ArecordRecorder) Stop() ([]float64, error) {
+	r.cmd.Process.Kill()
+	r.wg.Wait()
+	r.cmd.Wait()
+
+	return r.samples, r.err
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/hex"
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
 	"os"
@@ -10,6 +13,7 @@ import (
 	"time"
 
 	"github.com/Alextopher/itl-chorus/client/generators"
+	"github.com/Alextopher/itl-chorus/client/soundfont"
 	"github.com/Alextopher/itl-chorus/shared"
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/speaker"
@@ -17,7 +21,101 @@ import (
 
 var sr = beep.SampleRate(48000)
 
+// how much audio the speaker buffers before it is played
+const speakerBuffer = time.Second / 1000
+
+// how often the server is told about clipping, when there was any
+const clipReportInterval = time.Second
+
+// releaser is a note that can be let go of early
+type releaser interface {
+	Release()
+}
+
+// playing holds the most recent note for each key so it can be released early by a STOP
+var playing = make(map[uint8]releaser)
+
+// legatos holds the running oscillator of each voice when playing legato
+var legatos = make(map[uint32]*generators.Legato)
+
+// envelope replaces the envelope of every timbre when it isn't nil, a PLAY packet may still override it
+var envelope *generators.ADSR
+
+// font plays the instrument of PLAY packets that have one when it isn't nil
+var font *soundfont.SoundFont
+
+// mono ignores the pan of notes, for clients with a single speaker
+var mono bool
+
 func main() {
+	name := flag.String("name", "", "name this machine, the server shows it instead of the random identity")
+	statePath := flag.String("state", defaultStatePath(), "file that keeps the identity between runs")
+	latency := flag.Duration("latency", 0, "output latency of the sound card and speakers, the server plays this client early to make up for it")
+	selftestMode := flag.Bool("selftest", false, "measure the output latency by listening for a click with the microphone, replaces -latency")
+	capture := flag.String("capture", "", "WAV recording for -selftest to analyze instead of the microphone")
+	bandLimited := flag.Bool("bandlimited", false, "use band-limited square, sawtooth and triangle waves, which alias less on high notes")
+	legato := flag.Bool("legato", false, "keep one oscillator running per voice so back to back notes connect without restarting")
+	glide := flag.Duration("glide", 0, "with -legato, how long to slide from the pitch of one note to the next")
+	envelopeFlag := flag.String("envelope", "", "envelope for every voice as attack,decay,sustain,release (e.g. 10ms,100ms,0.7,200ms), replaces the defaults")
+	presets := flag.String("presets", "", "JSON file of additional timbres built from additive and FM synthesis (see presets.json)")
+	soundfontFile := flag.String("soundfont", "", "SF2 SoundFont to play the instruments of General MIDI songs with instead of the timbre (not used with -legato)")
+	effectsFlag := flag.String("effects", "", "effects everything is played through, e.g. highpass:cutoff=120;softclip:drive=1.5 (lowpass, highpass, delay, reverb, softclip)")
+	flag.IntVar(&maxVoices, "voices", 32, "most notes played at once, the oldest is cut short to make room for a new one (0 for no limit)")
+	headroom := flag.Float64("headroom", 0, "decibels to bring the mix down by before the limiter, for clients that play many notes at once")
+	dropLate := flag.Duration("drop-late", 0, "skip notes that arrive this much later than they should instead of playing them out of time (0 plays every note)")
+	flag.BoolVar(&mono, "mono", false, "play every note in the middle, ignoring its pan, for clients with a single speaker")
+	seed := flag.Int64("seed", 0, "seed for the noise of drums, plucked strings and noise timbres, so a song sounds the same every time (0 picks one at random)")
+	listTimbres := flag.Bool("timbres", false, "list the timbres this client can play and exit")
+	flag.Parse()
+
+	if *presets != "" {
+		if err := generators.LoadPresets(*presets); err != nil {
+			fmt.Println("Error loading presets:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *soundfontFile != "" {
+		var err error
+		font, err = soundfont.Load(*soundfontFile)
+		if err != nil {
+			fmt.Println("Error loading soundfont:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *listTimbres {
+		for voice, t := range generators.Timbres() {
+			fmt.Println(voice, t.Name)
+		}
+
+		if font != nil {
+			fmt.Println("SoundFont presets:")
+			for _, p := range font.Presets() {
+				fmt.Println(p)
+			}
+		}
+		return
+	}
+
+	generators.BandLimited = *bandLimited
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	generators.SetSeed(*seed)
+
+	if *envelopeFlag != "" {
+		e, err := shared.ParseEnvelope(*envelopeFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		a := adsr(e)
+		envelope = &a
+	}
+
 	if runtime.GOOS == "linux" {
 		// run these two commands to unmute the speakers
 		// amixer set Master 100%
@@ -35,12 +133,39 @@ func main() {
 	}
 
 	// initilize speaker
-	err := speaker.Init(sr, sr.N(time.Second/1000))
+	err := speaker.Init(sr, sr.N(speakerBuffer))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
+	// the server would have to play this client late, which it can't
+	if *latency < 0 {
+		fmt.Println("-latency must not be negative")
+		os.Exit(1)
+	}
+
+	if *selftestMode {
+		measured, err := selftest(*capture)
+		if err != nil {
+			fmt.Println("selftest:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Measured output latency:", measured)
+
+		// the measurement already includes the speaker buffer
+		*latency = measured - speakerBuffer
+		if *latency < 0 {
+			*latency = 0
+		}
+	}
+
+	if err := startOutput(*effectsFlag, *headroom); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	// initilize rng
 	rand.Seed(time.Now().UnixNano())
 
@@ -74,21 +199,33 @@ func main() {
 
 	fmt.Println("Listening on", conn.LocalAddr())
 
-	// Choose a random 24 byte identifier
-	var id [24]byte
-	_, err = rand.Read(id[:])
+	// Reuse the identity from the last run so the server recognizes this machine
+	id, err := loadIdentity(*statePath, *name)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
+	fmt.Println("Identity:", hex.EncodeToString(id[:]))
+
+	// tell the server how late our notes will sound
+	sendLatency := func(addr *net.UDPAddr) {
+		send <- shared.Message{
+			Pkt:  &shared.LATENCY_Packet{Latency: *latency + speakerBuffer + outputLatency()},
+			Addr: addr,
+		}
+	}
+
 Start:
-	speaker.Clear()
+	clearOutput()
+	playing = make(map[uint8]releaser)
+	legatos = make(map[uint32]*generators.Legato)
 
 	// Broadcast a CAPS packet until we get a response from the server
 	ticker := time.NewTicker(time.Second)
 
 	fmt.Println("Sending CAPS to", broadcastAddr, "...")
+	var server *net.UDPAddr
 Loop:
 	for {
 		select {
@@ -96,7 +233,7 @@ Loop:
 			send <- shared.Message{
 				Pkt: &shared.CAPS_Packet{
 					Name:      "gogo",
-					NumVoices: 1,
+					NumVoices: uint32(maxVoices),
 					Identity:  id,
 				},
 				Addr: broadcastAddr,
@@ -104,60 +241,260 @@ Loop:
 		case msg := <-recv:
 			if msg.Pkt.Type() == shared.PING {
 				fmt.Println("Received ping from", msg.Addr)
+				server = msg.Addr
+
+				sendLatency(msg.Addr)
+
+				// and which timbres we can play
+				for voice, t := range generators.Timbres() {
+					send <- shared.Message{
+						Pkt:  &shared.TIMBRE_Packet{Voice: uint32(voice), Name: t.Name},
+						Addr: msg.Addr,
+					}
+				}
 				break Loop
 			}
 		}
 	}
 
-	// Start listening for PLAY packets
-	for msg := range recv {
+	// Start listening for PLAY packets, telling the server now and then how it is going
+	stats = status{}
+	reports := time.NewTicker(clipReportInterval)
+	statuses := time.NewTicker(statusInterval)
+
+	for {
+		var msg shared.Message
+		select {
+		case msg = <-recv:
+		case <-reports.C:
+			if pkt := clipReport(); pkt != nil {
+				fmt.Println(pkt)
+				send <- shared.Message{Pkt: pkt, Addr: server}
+			}
+			continue
+		case <-statuses.C:
+			send <- shared.Message{Pkt: stats.report(underrunCount()), Addr: server}
+			continue
+		}
+
 		switch msg.Pkt.Type() {
 		case shared.PLAY:
 			pkt := msg.Pkt.(*shared.PLAY_Packet)
 			fmt.Println(pkt)
 
-			play(pkt)
+			if late(pkt.Due, *dropLate) {
+				break
+			}
+
+			var err error
+			if *legato {
+				err = playLegato(pkt, *glide)
+			} else {
+				err = play(pkt)
+			}
+			played(err)
+		case shared.DRUM:
+			pkt := msg.Pkt.(*shared.DRUM_Packet)
+			fmt.Println(pkt)
+
+			if late(pkt.Due, *dropLate) {
+				break
+			}
+
+			played(drum(pkt))
+		case shared.PARAM:
+			pkt := msg.Pkt.(*shared.PARAM_Packet)
+			fmt.Println(pkt)
+
+			before := outputLatency()
+			if err := setParam(pkt); err != nil {
+				fmt.Println(err)
+			}
+
+			// a longer look-ahead makes every note later, the server has to know
+			if outputLatency() != before {
+				sendLatency(server)
+			}
+		case shared.STOP:
+			pkt := msg.Pkt.(*shared.STOP_Packet)
+			fmt.Println(pkt)
+
+			stop(pkt)
+		case shared.PING:
+			// the server measures the round trip time with the echo, and asks again for the latency
+			// in case the first answer was lost
+			send <- shared.Message{Pkt: msg.Pkt, Addr: msg.Addr}
+			sendLatency(msg.Addr)
 		case shared.QUIT:
 			fmt.Println("Received QUIT from", msg.Addr)
+			reports.Stop()
+			statuses.Stop()
+
+			// the last word on how the song went
+			send <- shared.Message{Pkt: stats.report(underrunCount()), Addr: server}
 			goto Start
 		}
 	}
 }
 
-// play plays the given packet to the speakers
-func play(pkt *shared.PLAY_Packet) {
-	freq := float64(pkt.Frequency)
-	wl := int(float64(sr) / freq)
-
-	var g beep.Streamer
-	var err error
-
-	// voice encodes which generator to use for the note
-	switch pkt.Voice {
-	case 0:
-		g, err = generators.SineTone(sr, freq)
-	case 1:
-		g, err = generators.SawtoothTone(sr, freq)
-	case 2:
-		g, err = generators.SquareTone(sr, freq)
-	case 3:
-		g, err = generators.TriangleTone(sr, freq)
+// late counts a note that arrived and tells whether it is too late to be played, a limit of 0 plays every note
+func late(due uint32, limit time.Duration) bool {
+	l := stats.arrived(due, time.Now())
+	if limit > 0 && l > limit {
+		fmt.Println("Dropping a note", l, "late")
+		stats.dropped++
+		return true
 	}
+	return false
+}
 
-	// some notes are too short to play without popping
+// played counts a note as played, or as dropped when it couldn't be
+func played(err error) {
 	if err != nil {
 		fmt.Println(err)
+		stats.dropped++
 		return
 	}
+	stats.played++
+}
 
-	// play note until next event
+// play plays the given packet to the speakers
+func play(pkt *shared.PLAY_Packet) error {
+	var g generators.Generator
+	var adsr generators.ADSR
+	var err error
+	if font != nil && pkt.Instrument != nil {
+		g, adsr, err = instrument(pkt)
+	} else {
+		var t generators.Timbre
+		t, g, err = timbre(pkt)
+		adsr = t.Envelope
+	}
+	if err != nil {
+		return err
+	}
+
+	// play note until next event, the envelope fades it in and out so it doesn't pop
 	amp := &Amplitude{streamer: g, amplitude: float64(pkt.Amplitude)}
-	samples := sr.N(pkt.Duration)
+	env := generators.NewEnvelope(sr, amp, noteEnvelope(adsr, pkt), pkt.Duration)
+	playing[pkt.Key] = env
+
+	outputNote(panned(env, pkt.Pan), pkt.Voice)
+	return nil
+}
+
+// playLegato plays the packet on the voice's running generator
+func playLegato(pkt *shared.PLAY_Packet, glide time.Duration) error {
+	l, ok := legatos[pkt.Voice]
+	if !ok {
+		_, g, err := timbre(pkt)
+		if err != nil {
+			return err
+		}
+
+		// the voice stays where its first note was placed
+		l = generators.NewLegato(sr, g, glide)
+		legatos[pkt.Voice] = l
+		output(panned(l, pkt.Pan), pkt.Voice)
+	}
+
+	t, _ := generators.Lookup(pkt.Voice)
+
+	speaker.Lock()
+	l.Note(float64(pkt.Frequency), float64(pkt.Amplitude), pkt.Duration, noteEnvelope(t.Envelope, pkt))
+	speaker.Unlock()
+
+	playing[pkt.Key] = &legatoNote{legato: l, frequency: float64(pkt.Frequency)}
+	return nil
+}
+
+// timbre creates a generator for the packet's voice
+func timbre(pkt *shared.PLAY_Packet) (generators.Timbre, generators.Generator, error) {
+	t, ok := generators.Lookup(pkt.Voice)
+	if !ok {
+		return t, nil, fmt.Errorf("unknown voice %d", pkt.Voice)
+	}
+
+	g, err := t.New(sr, float64(pkt.Frequency))
+	return t, g, err
+}
+
+// drum plays a hit on the soundfont's drum kit, or on the synthesized drums when there is no kit
+func drum(pkt *shared.DRUM_Packet) error {
+	var s beep.Streamer
+	if font != nil {
+		// drum kits play every key at its own pitch
+		freq := 440 * math.Pow(2, (float64(pkt.Key)-69)/12)
+		g, adsr, err := font.Note(sr, soundfont.DrumBank, 0, pkt.Key, pkt.Velocity, freq)
+		if err == nil {
+			s = generators.NewEnvelope(sr, g, adsr, pkt.Duration)
+		}
+	}
+
+	if s == nil {
+		var err error
+		s, err = generators.Drum(sr, pkt.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	outputNote(panned(&Amplitude{streamer: s, amplitude: float64(pkt.Amplitude)}, pkt.Pan), shared.AllVoices)
+	return nil
+}
+
+// instrument creates a generator for the packet's instrument from the soundfont, along with its envelope
+func instrument(pkt *shared.PLAY_Packet) (generators.Generator, generators.ADSR, error) {
+	// the key decides which samples are used, the frequency is still played exactly
+	return font.Note(sr, 0, pkt.Instrument.Program, pkt.Key, pkt.Instrument.Velocity, float64(pkt.Frequency))
+}
+
+// noteEnvelope picks the envelope from the packet, the -envelope flag or the timbre, in that order
+func noteEnvelope(def generators.ADSR, pkt *shared.PLAY_Packet) generators.ADSR {
+	switch {
+	case pkt.Envelope != nil:
+		return adsr(*pkt.Envelope)
+	case envelope != nil:
+		return *envelope
+	default:
+		return def
+	}
+}
+
+// legatoNote is a note on a legato voice, releasing it does nothing once the voice moved on to another note
+type legatoNote struct {
+	legato    *generators.Legato
+	frequency float64
+}
+
+func (n *legatoNote) Release() {
+	if n.legato.Frequency() == n.frequency {
+		n.legato.Release()
+	}
+}
+
+// stop releases the note playing the packet's key
+func stop(pkt *shared.STOP_Packet) {
+	env, ok := playing[pkt.Key]
+	if !ok {
+		return
+	}
 
-	// make sure we play an integer number of cycles to avoid "popping"
-	samples = (samples / wl) * wl
+	speaker.Lock()
+	env.Release()
+	speaker.Unlock()
 
-	speaker.Play(beep.Take(samples, amp))
+	delete(playing, pkt.Key)
+}
+
+// adsr converts an envelope from the protocol to the generators
+func adsr(e shared.Envelope) generators.ADSR {
+	return generators.ADSR{
+		Attack:  e.Attack,
+		Decay:   e.Decay,
+		Sustain: float64(e.Sustain),
+		Release: e.Release,
+	}
 }
 
 type Amplitude struct {
@@ -178,3 +515,39 @@ func (g *Amplitude) Stream(samples [][2]float64) (n int, ok bool) {
 func (g *Amplitude) Err() error {
 	return g.streamer.Err()
 }
+
+// Pan places a streamer between the speakers. The generators are mono, they write the same
+// value into both channels, so this is all it takes to move a note to one side.
+type Pan struct {
+	streamer    beep.Streamer
+	left, right float64
+}
+
+// panned places s at pan between -1 (left) and 1 (right) with an equal power law, scaled so
+// a note in the middle is as loud as it was before. Neither side gets louder than the note itself,
+// so panning can't take a note over full scale.
+func panned(s beep.Streamer, pan float32) beep.Streamer {
+	if mono || pan == 0 {
+		return s
+	}
+
+	angle := (math.Max(-1, math.Min(1, float64(pan))) + 1) * math.Pi / 4
+	return &Pan{
+		streamer: s,
+		left:     math.Min(1, math.Sqrt2*math.Cos(angle)),
+		right:    math.Min(1, math.Sqrt2*math.Sin(angle)),
+	}
+}
+
+func (p *Pan) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = p.streamer.Stream(samples)
+	for i := range samples[:n] {
+		samples[i][0] *= p.left
+		samples[i][1] *= p.right
+	}
+	return n, ok
+}
+
+func (p *Pan) Err() error {
+	return p.streamer.Err()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+func TestPanned(t *testing.T) {
+	tests := []struct {
+		pan         float32
+		left, right float64
+	}{
+		{0, 1, 1},
+		{-1, 1, 0},
+		{1, 0, 1},
+		{2, 0, 1},
+		// halfway to the right the right side is as loud as the note, the left quieter
+		{0.5, math.Sqrt2 * math.Cos(3*math.Pi/8), 1},
+	}
+
+	for _, test := range tests {
+		samples := make([][2]float64, 1)
+		panned(constant(1, 1), test.pan).Stream(samples)
+
+		if math.Abs(samples[0][0]-test.left) > 1e-9 || math.Abs(samples[0][1]-test.right) > 1e-9 {
+			t.Errorf("Pan %v: expected %v left and %v right, got %v", test.pan, test.left, test.right, samples[0])
+		}
+	}
+}
+
+// the lowest keys round down to the same frequency, STOP tells them apart by key
+func TestStopKey(t *testing.T) {
+	if err := startOutput("", 0); err != nil {
+		t.Fatal(err)
+	}
+	clearOutput()
+	playing = make(map[uint8]releaser)
+
+	for key := uint8(0); key < 2; key++ {
+		err := play(&shared.PLAY_Packet{Duration: time.Second, Frequency: 8, Amplitude: 0.1, Voice: 1, Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop(&shared.STOP_Packet{Frequency: 8, Key: 1})
+	if _, ok := playing[0]; !ok || len(playing) != 1 {
+		t.Errorf("Expected only key 0 left playing, got %v", playing)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/client/effects"
+	"github.com/Alextopher/itl-chorus/client/generators"
+	"github.com/Alextopher/itl-chorus/shared"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+)
+
+// bus mixes the notes that share an effects chain
+type bus struct {
+	mixer *beep.Mixer
+	chain *effects.Chain
+}
+
+func newBus(spec string) (*bus, error) {
+	chain, err := effects.Parse(sr, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bus{mixer: &beep.Mixer{}, chain: chain}, nil
+}
+
+// master is the bus everything is played through, buses holds the bus of each voice
+var (
+	master *bus
+	buses  = make(map[uint32]*bus)
+)
+
+// limiter keeps the mix under full scale after the global effects, level is the gain of the mix
+// before it, below 1 to leave headroom for notes that pile up
+var (
+	limiter *effects.Limiter
+	level   = 1.0
+)
+
+// out is what the speaker plays, the master bus after its effects and the limiter
+var out beep.Streamer
+
+// startOutput sets up the buses and starts playing them, the global effects go on the master bus.
+// headroom is how many decibels the mix is brought down before the limiter.
+func startOutput(global string, headroom float64) error {
+	var err error
+	master, err = newBus(global)
+	if err != nil {
+		return fmt.Errorf("effects: %w", err)
+	}
+
+	limiter = effects.NewLimiter(sr)
+	level = math.Pow(10, -headroom/20)
+
+	for voice, t := range generators.Timbres() {
+		if t.Effects == "" {
+			continue
+		}
+
+		b, err := newBus(t.Effects)
+		if err != nil {
+			return fmt.Errorf("effects of %s: %w", t.Name, err)
+		}
+
+		buses[uint32(voice)] = b
+		master.mixer.Add(b.chain.Streamer(b.mixer))
+	}
+
+	mix := master.chain.Streamer(master.mixer)
+	out = beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		countUnderruns(len(samples))
+
+		n, ok = mix.Stream(samples)
+		for i := range samples[:n] {
+			samples[i][0] *= level
+			samples[i][1] *= level
+		}
+		limiter.Process(samples[:n])
+		return n, ok
+	})
+	speaker.Play(out)
+	return nil
+}
+
+// underruns counts the times the sound card seemed to run out of sound, lastStream is when it last
+// asked for more and lastLen how long that lasted
+var (
+	underruns  uint32
+	lastStream time.Time
+	lastLen    time.Duration
+)
+
+// asking for more this much later than the last of the sound would have run out counts as an underrun
+const underrunTolerance = 10 * time.Millisecond
+
+// countUnderruns is called every time the sound card asks for n samples. It can only guess, a sound
+// card that asks late might have had more buffered than it said.
+func countUnderruns(n int) {
+	now := time.Now()
+	if !lastStream.IsZero() && now.Sub(lastStream) > lastLen+underrunTolerance {
+		underruns++
+	}
+	lastStream, lastLen = now, sr.D(n)
+}
+
+// clearOutput stops every note while leaving the buses playing
+func clearOutput() {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	underruns = 0
+
+	master.mixer.Clear()
+	for _, b := range buses {
+		b.mixer.Clear()
+		master.mixer.Add(b.chain.Streamer(b.mixer))
+	}
+	notes = nil
+}
+
+// output plays s through the effects of the voice
+func output(s beep.Streamer, voice uint32) {
+	b, ok := buses[voice]
+	if !ok {
+		b = master
+	}
+
+	speaker.Lock()
+	b.mixer.Add(s)
+	speaker.Unlock()
+}
+
+// maxVoices is the most notes played at once, 0 for no limit. The oldest note is cut short to make room.
+var maxVoices int
+
+// notes are the notes playing under the voice limit, oldest first, stolen counts the ones cut short
+var (
+	notes  []*note
+	stolen int
+)
+
+// how long a note that is cut short takes to fade out, so it doesn't click
+const stealFade = 5 * time.Millisecond
+
+// note is a note that can be cut short to make room for another
+type note struct {
+	streamer beep.Streamer
+	// fade is how many samples are left of the fade out once the note is cut short, -1 until then
+	fade int
+	done bool
+}
+
+func (n *note) Stream(samples [][2]float64) (int, bool) {
+	if n.done {
+		return 0, false
+	}
+
+	k, ok := n.streamer.Stream(samples)
+	if n.fade >= 0 {
+		total := float64(sr.N(stealFade))
+		for i := range samples[:k] {
+			g := float64(n.fade) / total
+			samples[i][0] *= g
+			samples[i][1] *= g
+
+			if n.fade > 0 {
+				n.fade--
+			}
+		}
+
+		if n.fade == 0 {
+			ok = false
+		}
+	}
+
+	n.done = !ok
+	return k, ok
+}
+
+func (n *note) Err() error {
+	return n.streamer.Err()
+}
+
+// outputNote plays a note through the effects of the voice, cutting the oldest note short when
+// there are already maxVoices playing
+func outputNote(s beep.Streamer, voice uint32) {
+	n := &note{streamer: s, fade: -1}
+
+	speaker.Lock()
+	// forget the notes that ended on their own
+	playing := notes[:0]
+	for _, old := range notes {
+		if !old.done {
+			playing = append(playing, old)
+		}
+	}
+	notes = playing
+
+	if maxVoices > 0 {
+		for len(notes) >= maxVoices {
+			notes[0].fade = sr.N(stealFade)
+			notes = notes[1:]
+			stolen++
+		}
+	}
+	notes = append(notes, n)
+	speaker.Unlock()
+
+	output(n, voice)
+}
+
+// clipReport says how often the mix went over full scale and how many notes were cut short since the
+// last report, or nil when neither happened
+func clipReport() *shared.CLIP_Packet {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	clips, peak := limiter.Clips()
+	if clips == 0 && stolen == 0 {
+		return nil
+	}
+
+	pkt := &shared.CLIP_Packet{Clips: uint32(clips), Peak: float32(peak), Stolen: uint32(stolen)}
+	stolen = 0
+	return pkt
+}
+
+// underrunCount is the number of underruns since the output was last cleared
+func underrunCount() uint32 {
+	speaker.Lock()
+	defer speaker.Unlock()
+	return underruns
+}
+
+// outputLatency is how long the mix is held back on its way to the speaker by the limiter and the
+// global effects. The effects of a single timbre only hold back its own notes and are left out.
+func outputLatency() time.Duration {
+	speaker.Lock()
+	defer speaker.Unlock()
+	return limiter.Latency() + master.chain.Latency()
+}
+
+// setParam changes an effect parameter of a voice, or of everything, as the server asks.
+// A voice without effects gets a bus of its own, the limiter parameters of everything go to the
+// limiter in front of the speaker.
+func setParam(pkt *shared.PARAM_Packet) error {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	if pkt.Voice == shared.AllVoices {
+		if param := strings.TrimPrefix(pkt.Param, "limiter."); param != pkt.Param {
+			return limiter.Set(param, float64(pkt.Value))
+		}
+		return master.chain.Set(pkt.Param, float64(pkt.Value))
+	}
+
+	if _, ok := generators.Lookup(pkt.Voice); !ok {
+		return fmt.Errorf("unknown voice %d", pkt.Voice)
+	}
+
+	b, ok := buses[pkt.Voice]
+	if !ok {
+		var err error
+		b, err = newBus("")
+		if err != nil {
+			return err
+		}
+
+		buses[pkt.Voice] = b
+		master.mixer.Add(b.chain.Streamer(b.mixer))
+	}
+
+	return b.chain.Set(pkt.Param, float64(pkt.Value))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+	"github.com/faiface/beep"
+)
+
+// constant plays value on both sides for n samples
+func constant(value float64, n int) beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if n <= 0 {
+			return 0, false
+		}
+
+		k := len(samples)
+		if k > n {
+			k = n
+		}
+		for i := range samples[:k] {
+			samples[i] = [2]float64{value, value}
+		}
+		n -= k
+		return k, true
+	})
+}
+
+// sum adds up the left side of what the speaker plays next, notes come out of the limiter late
+func sum(n int) float64 {
+	samples := make([][2]float64, n)
+	out.Stream(samples)
+
+	total := 0.0
+	for _, s := range samples {
+		total += s[0]
+	}
+	return total
+}
+
+func TestOutput(t *testing.T) {
+	if err := startOutput("", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new song clears the notes, the buses have to keep playing
+	clearOutput()
+	output(constant(0.5, 64), 0)
+
+	if total := sum(4096); math.Abs(total-32) > 1e-9 {
+		t.Errorf("Expected the note to reach the speaker whole, got %v of 32", total)
+	}
+
+	output(constant(0.5, 64), 0)
+	clearOutput()
+
+	if total := sum(4096); total != 0 {
+		t.Errorf("Expected clearing the output to stop the note, got %v", total)
+	}
+}
+
+func TestNoteFade(t *testing.T) {
+	n := &note{streamer: constant(1, sr.N(time.Second)), fade: -1}
+
+	samples := make([][2]float64, 100)
+	if k, ok := n.Stream(samples); k != 100 || !ok || samples[99][0] != 1 {
+		t.Fatalf("Expected the note to play untouched, got %d samples ending in %v", k, samples[99])
+	}
+
+	// cut short, it fades out instead of clicking
+	fade := sr.N(stealFade)
+	n.fade = fade
+	samples = make([][2]float64, 2*fade)
+	_, ok := n.Stream(samples)
+	if ok {
+		t.Error("Expected the note to end once it faded out")
+	}
+	if samples[0][0] != 1 || samples[fade/2][0] != 0.5 || samples[fade][0] != 0 {
+		t.Errorf("Expected a fade from 1 to 0, got %v, %v and %v", samples[0][0], samples[fade/2][0], samples[fade][0])
+	}
+
+	if k, ok := n.Stream(samples); k != 0 || ok {
+		t.Error("Expected nothing more from the note")
+	}
+}
+
+func TestVoiceLimit(t *testing.T) {
+	if err := startOutput("", 0); err != nil {
+		t.Fatal(err)
+	}
+	clearOutput()
+	clipReport()
+
+	maxVoices = 2
+	defer func() { maxVoices = 0 }()
+
+	for i := 0; i < 3; i++ {
+		outputNote(constant(0.1, sr.N(time.Second)), 0)
+	}
+
+	// the oldest note makes room for the third
+	if len(notes) != 2 || stolen != 1 {
+		t.Fatalf("Expected 2 notes playing and 1 stolen, got %d and %d", len(notes), stolen)
+	}
+
+	// once faded out two notes are left
+	sum(sr.N(50 * time.Millisecond))
+	if total := sum(100); math.Abs(total-100*0.2) > 1e-9 {
+		t.Errorf("Expected 2 notes playing, got a level of %v", total/100)
+	}
+
+	pkt := clipReport()
+	if pkt == nil || pkt.Stolen != 1 || pkt.Clips != 0 {
+		t.Fatalf("Expected a report of 1 stolen note, got %v", pkt)
+	}
+	if pkt := clipReport(); pkt != nil {
+		t.Errorf("Expected nothing new to report, got %v", pkt)
+	}
+}
+
+func TestClipReport(t *testing.T) {
+	if err := startOutput("", 0); err != nil {
+		t.Fatal(err)
+	}
+	clearOutput()
+	clipReport()
+
+	output(constant(2, sr.N(10*time.Millisecond)), 0)
+	sum(sr.N(50 * time.Millisecond))
+
+	pkt := clipReport()
+	if pkt == nil || pkt.Clips != 1 || pkt.Peak != 2 {
+		t.Fatalf("Expected a report of 1 clip peaking at 2, got %v", pkt)
+	}
+}
+
+func TestOutputLatency(t *testing.T) {
+	if err := startOutput("", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := outputLatency(); l != 5*time.Millisecond {
+		t.Errorf("Expected the limiter to hold the output back 5ms, got %v", l)
+	}
+
+	// the server sets the look-ahead of the limiter in front of the speaker
+	err := setParam(&shared.PARAM_Packet{Voice: shared.AllVoices, Param: "limiter.lookahead", Value: 0.025})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := outputLatency(); l != 25*time.Millisecond {
+		t.Errorf("Expected a longer look-ahead to hold the output back 25ms, got %v", l)
+	}
+	if master.chain.Len() != 0 {
+		t.Error("Expected no second limiter on the master bus")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Alextopher/itl-chorus/client/loopback"
+	"github.com/faiface/beep/speaker"
+)
+
+// selftest plays a click and listens for it to measure the output latency from end to end.
+// If capture is set that WAV recording is analyzed instead of listening to the microphone,
+// it must have started recording when the self-test started.
+func selftest(capture string) (time.Duration, error) {
+	var rec loopback.Recorder
+	if capture != "" {
+		rec = &loopback.WAVRecorder{Filename: capture, SampleRate: sr}
+	} else {
+		rec = &loopback.ArecordRecorder{SampleRate: sr}
+	}
+
+	// give the recording a moment to settle before the click
+	const playAt = time.Second / 2
+
+	click := loopback.Click(sr)
+	started, err := rec.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	time.Sleep(time.Until(started.Add(playAt)))
+	speaker.Play(loopback.Streamer(click))
+
+	// long enough to hear the click through even a bluetooth speaker
+	time.Sleep(time.Second * 2)
+
+	samples, err := rec.Stop()
+	if err != nil {
+		return 0, err
+	}
+
+	return loopback.Measure(samples, sr, click, playAt)
+}
@@ -0,0 +1,255 @@
+package soundfont
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/client/generators"
+	"github.com/faiface/beep"
+)
+
+// generatorValues looks up generators through the zones of a note: the instrument zone, its global zone
+// and the defaults, plus the offsets of the preset zone and its global zone for the tuning, attenuation
+// and envelope
+type generatorValues struct {
+	izone, iglobal, pzone, pglobal *zone
+}
+
+// instrument is the value set on the instrument level
+func (v generatorValues) instrument(op int, def int16) int16 {
+	for _, z := range []*zone{v.izone, v.iglobal} {
+		if z == nil {
+			continue
+		}
+		if g, ok := z.get(op); ok {
+			return g
+		}
+	}
+	return def
+}
+
+// value is the instrument value offset by the preset
+func (v generatorValues) value(op int, def int16) int {
+	value := int(v.instrument(op, def))
+	for _, z := range []*zone{v.pzone, v.pglobal} {
+		if z == nil {
+			continue
+		}
+		if g, ok := z.get(op); ok {
+			return value + int(g)
+		}
+	}
+	return value
+}
+
+// inRange checks the ranges of a zone, falling back to its global zone
+func inRange(z, global *zone, op int, v uint8) bool {
+	if _, ok := z.get(op); !ok && global != nil {
+		return global.inRange(op, v)
+	}
+	return z.inRange(op, v)
+}
+
+// global splits off the global zone, which is the first zone when it lacks the generator every other zone has
+func global(zones []zone, op int) (*zone, []zone) {
+	if len(zones) > 0 && !zones[0].set[op] {
+		return &zones[0], zones[1:]
+	}
+	return nil, zones
+}
+
+// Note creates the generator and envelope for a key played on a program.
+// Every zone the key and velocity fall in is layered, the envelope is the one of the first zone.
+func (sf *SoundFont) Note(sr beep.SampleRate, bank uint16, program, key, vel uint8, freq float64) (generators.Generator, generators.ADSR, error) {
+	var adsr generators.ADSR
+
+	p, err := sf.find(bank, uint16(program))
+	if err != nil {
+		return nil, adsr, err
+	}
+
+	l := &layers{}
+	pglobal, pzones := global(p.zones, genInstrument)
+	for i := range pzones {
+		pzone := &pzones[i]
+		if !pzone.set[genInstrument] || !inRange(pzone, pglobal, genKeyRange, key) || !inRange(pzone, pglobal, genVelRange, vel) {
+			continue
+		}
+
+		id := int(uint16(pzone.gens[genInstrument]))
+		if id >= len(sf.instruments) {
+			return nil, adsr, fmt.Errorf("soundfont: preset %q uses missing instrument %d", p.name, id)
+		}
+
+		iglobal, izones := global(sf.instruments[id].zones, genSampleID)
+		for j := range izones {
+			izone := &izones[j]
+			if !izone.set[genSampleID] || !inRange(izone, iglobal, genKeyRange, key) || !inRange(izone, iglobal, genVelRange, vel) {
+				continue
+			}
+
+			v := generatorValues{izone: izone, iglobal: iglobal, pzone: pzone, pglobal: pglobal}
+			g, gain, err := sf.layer(sr, v, freq)
+			if err != nil {
+				return nil, adsr, fmt.Errorf("soundfont: preset %q: %w", p.name, err)
+			}
+
+			if len(l.gens) == 0 {
+				adsr = envelope(v)
+			}
+
+			l.gens = append(l.gens, g)
+			l.gains = append(l.gains, gain)
+		}
+	}
+
+	if len(l.gens) == 0 {
+		return nil, adsr, fmt.Errorf("soundfont: preset %q has nothing for key %d velocity %d", p.name, key, vel)
+	}
+
+	return l, adsr, nil
+}
+
+// layer creates the generator for a single zone
+func (sf *SoundFont) layer(sr beep.SampleRate, v generatorValues, freq float64) (generators.Generator, float64, error) {
+	id := int(uint16(v.izone.gens[genSampleID]))
+	if id >= len(sf.samples) {
+		return nil, 0, fmt.Errorf("missing sample %d", id)
+	}
+	h := sf.samples[id]
+
+	start, end, loopStart, loopEnd := addresses(h, v)
+	if start < 0 || start >= end || end > len(sf.data) {
+		return nil, 0, fmt.Errorf("sample %q is outside the sample data", h.name)
+	}
+
+	sample := &generators.Sample{
+		Data:       sf.sampleData(start, end),
+		SampleRate: h.sampleRate,
+	}
+
+	// mode 1 loops forever and mode 3 until the release, the envelope fades both out the same way
+	if mode := v.instrument(genSampleModes, 0); mode&1 != 0 && loopStart >= start && loopStart < loopEnd && loopEnd <= end {
+		sample.LoopStart = loopStart - start
+		sample.LoopEnd = loopEnd - start
+	}
+
+	root := int(v.instrument(genOverridingRootKey, -1))
+	if root < 0 {
+		root = int(h.originalPitch)
+	}
+	if root > 127 {
+		root = 60
+	}
+
+	// tuning raises the pitch, which is the same as the sample being recorded lower
+	cents := v.value(genCoarseTune, 0)*100 + v.value(genFineTune, 0) + int(h.pitchCorrection)
+	sample.Root = 440 * math.Pow(2, (float64(root)-float64(cents)/100-69)/12)
+
+	g, err := generators.Sampler([]*generators.Sample{sample})(sr, freq)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// attenuation is in centibels
+	attenuation := math.Max(0, float64(v.value(genInitialAttenuation, 0)))
+	return g, math.Pow(10, -attenuation/200), nil
+}
+
+// addresses is where the sample and its loop start and end in the sample data. Only instruments
+// move them, SF2 doesn't allow presets to offset the addresses.
+func addresses(h sampleHeader, v generatorValues) (start, end, loopStart, loopEnd int) {
+	offset := func(fine, coarse int) int {
+		return int(v.instrument(fine, 0)) + 32768*int(v.instrument(coarse, 0))
+	}
+
+	start = int(h.start) + offset(genStartAddrsOffset, genStartAddrsCoarseOffset)
+	end = int(h.end) + offset(genEndAddrsOffset, genEndAddrsCoarseOffset)
+	loopStart = int(h.startLoop) + offset(genStartloopAddrsOffset, genStartloopCoarseOffset)
+	loopEnd = int(h.endLoop) + offset(genEndloopAddrsOffset, genEndloopCoarseOffset)
+	return start, end, loopStart, loopEnd
+}
+
+// sampleData converts the samples between start and end, the conversions are kept for the next notes
+func (sf *SoundFont) sampleData(start, end int) [][2]float64 {
+	if sf.cache == nil {
+		sf.cache = make(map[[2]int][][2]float64)
+	}
+
+	k := [2]int{start, end}
+	if data, ok := sf.cache[k]; ok {
+		return data
+	}
+
+	data := make([][2]float64, end-start)
+	for i, s := range sf.data[start:end] {
+		v := float64(s) / 32768
+		data[i] = [2]float64{v, v}
+	}
+
+	sf.cache[k] = data
+	return data
+}
+
+// envelope reads the volume envelope of a zone, times are in timecents and the sustain in centibels
+func envelope(v generatorValues) generators.ADSR {
+	timecents := func(op int) time.Duration {
+		tc := v.value(op, -12000)
+		return time.Duration(math.Pow(2, float64(tc)/1200) * float64(time.Second))
+	}
+
+	sustain := math.Max(0, math.Min(1440, float64(v.value(genSustainVolEnv, 0))))
+
+	return generators.ADSR{
+		Attack:  timecents(genAttackVolEnv),
+		Decay:   timecents(genDecayVolEnv),
+		Sustain: math.Pow(10, -sustain/200),
+		Release: timecents(genReleaseVolEnv),
+	}
+}
+
+// layers plays the generators of every zone of a note together
+type layers struct {
+	gens  []generators.Generator
+	gains []float64
+	buf   [][2]float64
+}
+
+func (l *layers) SetFrequency(freq float64) {
+	for _, g := range l.gens {
+		g.SetFrequency(freq)
+	}
+}
+
+// Phase is always 0, samples have no phase to keep
+func (l *layers) Phase() float64 {
+	return 0
+}
+
+func (l *layers) SetPhase(t float64) {}
+
+func (l *layers) Stream(samples [][2]float64) (n int, ok bool) {
+	if len(l.buf) < len(samples) {
+		l.buf = make([][2]float64, len(samples))
+	}
+
+	for i := range samples {
+		samples[i] = [2]float64{}
+	}
+
+	for i, g := range l.gens {
+		buf := l.buf[:len(samples)]
+		g.Stream(buf)
+		for j := range buf {
+			samples[j][0] += buf[j][0] * l.gains[i]
+			samples[j][1] += buf[j][1] * l.gains[i]
+		}
+	}
+
+	return len(samples), true
+}
+
+func (l *layers) Err() error {
+	return nil
+}
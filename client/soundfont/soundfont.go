@@ -0,0 +1,321 @@
+// Package soundfont plays the presets of SF2 SoundFonts.
+// Only what is needed to pick a sample and play it is read: key and velocity zones, tuning,
+// loops, attenuation and the volume envelope. Modulators and the other generators are ignored.
+package soundfont
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/faiface/beep"
+)
+
+// DrumBank is the bank of the General MIDI drum kits
+const DrumBank = 128
+
+// SoundFont is a parsed SF2 file
+type SoundFont struct {
+	presets     []preset
+	instruments []instrument
+	samples     []sampleHeader
+
+	// 16 bit sample data of every sample in the file
+	data []int16
+	// samples converted for playing, by start and end
+	cache map[[2]int][][2]float64
+}
+
+type preset struct {
+	name    string
+	program uint16
+	bank    uint16
+	zones   []zone
+}
+
+type instrument struct {
+	name  string
+	zones []zone
+}
+
+type sampleHeader struct {
+	name               string
+	start, end         uint32
+	startLoop, endLoop uint32
+	sampleRate         beep.SampleRate
+	originalPitch      uint8
+	pitchCorrection    int8
+}
+
+// generator operators that are used
+const (
+	genStartAddrsOffset       = 0
+	genEndAddrsOffset         = 1
+	genStartloopAddrsOffset   = 2
+	genEndloopAddrsOffset     = 3
+	genStartAddrsCoarseOffset = 4
+	genEndAddrsCoarseOffset   = 12
+	genAttackVolEnv           = 34
+	genDecayVolEnv            = 36
+	genSustainVolEnv          = 37
+	genReleaseVolEnv          = 38
+	genInstrument             = 41
+	genKeyRange               = 43
+	genVelRange               = 44
+	genStartloopCoarseOffset  = 45
+	genInitialAttenuation     = 48
+	genEndloopCoarseOffset    = 50
+	genCoarseTune             = 51
+	genFineTune               = 52
+	genSampleID               = 53
+	genSampleModes            = 54
+	genOverridingRootKey      = 58
+
+	genCount = 61
+)
+
+// zone is a set of generators, unset generators take their value from the global zone or the default
+type zone struct {
+	gens [genCount]int16
+	set  [genCount]bool
+}
+
+func (z *zone) get(op int) (int16, bool) {
+	return z.gens[op], z.set[op]
+}
+
+// ranges are stored as two bytes, low then high
+func (z *zone) inRange(op int, v uint8) bool {
+	g, ok := z.get(op)
+	if !ok {
+		return true
+	}
+
+	lo, hi := uint8(uint16(g)), uint8(uint16(g)>>8)
+	return lo <= v && v <= hi
+}
+
+// Load reads an SF2 file
+func Load(filename string) (*SoundFont, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	sf, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return sf, nil
+}
+
+// chunk is a RIFF chunk
+type chunk struct {
+	id   string
+	data []byte
+}
+
+// chunks splits data into RIFF chunks
+func chunks(data []byte) ([]chunk, error) {
+	var cs []chunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("truncated chunk header")
+		}
+
+		id := string(data[:4])
+		size := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+		if uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("chunk %q is truncated", id)
+		}
+
+		cs = append(cs, chunk{id: id, data: data[:size]})
+
+		// chunks are padded to an even size
+		size += size & 1
+		if uint64(size) > uint64(len(data)) {
+			size = uint32(len(data))
+		}
+		data = data[size:]
+	}
+
+	return cs, nil
+}
+
+// Parse parses the contents of an SF2 file
+func Parse(b []byte) (*SoundFont, error) {
+	riff, err := chunks(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(riff) != 1 || riff[0].id != "RIFF" || len(riff[0].data) < 4 || string(riff[0].data[:4]) != "sfbk" {
+		return nil, errors.New("not a SoundFont")
+	}
+
+	lists, err := chunks(riff[0].data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	// the chunks of the sdta and pdta lists by id
+	sub := make(map[string][]byte)
+	for _, list := range lists {
+		if list.id != "LIST" || len(list.data) < 4 {
+			continue
+		}
+
+		kind := string(list.data[:4])
+		if kind != "sdta" && kind != "pdta" {
+			continue
+		}
+
+		cs, err := chunks(list.data[4:])
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cs {
+			sub[c.id] = c.data
+		}
+	}
+
+	for _, id := range []string{"smpl", "phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := sub[id]; !ok {
+			return nil, fmt.Errorf("missing %s chunk", id)
+		}
+	}
+
+	sf := &SoundFont{}
+
+	sf.data = make([]int16, len(sub["smpl"])/2)
+	binary.Read(bytes.NewReader(sub["smpl"]), binary.LittleEndian, sf.data)
+
+	// sample headers, the last one is the terminal record
+	shdr := sub["shdr"]
+	for i := 0; i+46 <= len(shdr)-46; i += 46 {
+		r := shdr[i : i+46]
+		sf.samples = append(sf.samples, sampleHeader{
+			name:            name(r[:20]),
+			start:           binary.LittleEndian.Uint32(r[20:]),
+			end:             binary.LittleEndian.Uint32(r[24:]),
+			startLoop:       binary.LittleEndian.Uint32(r[28:]),
+			endLoop:         binary.LittleEndian.Uint32(r[32:]),
+			sampleRate:      beep.SampleRate(binary.LittleEndian.Uint32(r[36:])),
+			originalPitch:   r[40],
+			pitchCorrection: int8(r[41]),
+		})
+	}
+
+	// instruments
+	instZones, err := zones(sub["ibag"], sub["igen"])
+	if err != nil {
+		return nil, fmt.Errorf("instrument zones: %w", err)
+	}
+
+	inst := sub["inst"]
+	for i := 0; i+22 <= len(inst)-22; i += 22 {
+		from := int(binary.LittleEndian.Uint16(inst[i+20:]))
+		to := int(binary.LittleEndian.Uint16(inst[i+22+20:]))
+		if from > to || to > len(instZones) {
+			return nil, fmt.Errorf("instrument %d has invalid zones", i/22)
+		}
+
+		sf.instruments = append(sf.instruments, instrument{
+			name:  name(inst[i : i+20]),
+			zones: instZones[from:to],
+		})
+	}
+
+	// presets
+	presetZones, err := zones(sub["pbag"], sub["pgen"])
+	if err != nil {
+		return nil, fmt.Errorf("preset zones: %w", err)
+	}
+
+	phdr := sub["phdr"]
+	for i := 0; i+38 <= len(phdr)-38; i += 38 {
+		from := int(binary.LittleEndian.Uint16(phdr[i+24:]))
+		to := int(binary.LittleEndian.Uint16(phdr[i+38+24:]))
+		if from > to || to > len(presetZones) {
+			return nil, fmt.Errorf("preset %d has invalid zones", i/38)
+		}
+
+		sf.presets = append(sf.presets, preset{
+			name:    name(phdr[i : i+20]),
+			program: binary.LittleEndian.Uint16(phdr[i+20:]),
+			bank:    binary.LittleEndian.Uint16(phdr[i+22:]),
+			zones:   presetZones[from:to],
+		})
+	}
+
+	return sf, nil
+}
+
+// zones reads the bags and their generators
+func zones(bags, gens []byte) ([]zone, error) {
+	if len(bags) < 4 {
+		return nil, errors.New("no terminal bag")
+	}
+
+	n := len(bags)/4 - 1
+	zs := make([]zone, n)
+	for i := range zs {
+		from := int(binary.LittleEndian.Uint16(bags[i*4:]))
+		to := int(binary.LittleEndian.Uint16(bags[i*4+4:]))
+		if from > to || to*4 > len(gens) {
+			return nil, fmt.Errorf("bag %d has invalid generators", i)
+		}
+
+		for j := from; j < to; j++ {
+			op := int(binary.LittleEndian.Uint16(gens[j*4:]))
+			if op >= genCount {
+				continue
+			}
+
+			zs[i].gens[op] = int16(binary.LittleEndian.Uint16(gens[j*4+2:]))
+			zs[i].set[op] = true
+		}
+	}
+
+	return zs, nil
+}
+
+// name reads a zero terminated name
+func name(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// Presets lists the presets as "bank:program name"
+func (sf *SoundFont) Presets() []string {
+	names := make([]string, len(sf.presets))
+	for i, p := range sf.presets {
+		names[i] = fmt.Sprintf("%d:%d %s", p.bank, p.program, p.name)
+	}
+	return names
+}
+
+// find finds the preset for a program, falling back to bank 0 unless it is looking for drums
+func (sf *SoundFont) find(bank, program uint16) (*preset, error) {
+	banks := []uint16{bank, 0}
+	if bank == DrumBank {
+		banks = banks[:1]
+	}
+
+	for _, b := range banks {
+		for i := range sf.presets {
+			if sf.presets[i].bank == b && sf.presets[i].program == program {
+				return &sf.presets[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("soundfont: no preset for bank %d program %d", bank, program)
+}
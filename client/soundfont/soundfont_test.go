@@ -0,0 +1,171 @@
+package soundfont
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// riff builds a chunk, lists pass their kind as the start of data
+func riff(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := &bytes.Buffer{}
+	b.WriteString(id)
+	binary.Write(b, binary.LittleEndian, uint32(len(body)))
+	b.Write(body)
+	if len(body)%2 == 1 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func record(fields ...interface{}) []byte {
+	b := &bytes.Buffer{}
+	for _, f := range fields {
+		if s, ok := f.(string); ok {
+			name := make([]byte, 20)
+			copy(name, s)
+			b.Write(name)
+			continue
+		}
+		binary.Write(b, binary.LittleEndian, f)
+	}
+	return b.Bytes()
+}
+
+type gen struct {
+	op     uint16
+	amount int16
+}
+
+func gens(gs ...gen) []byte {
+	return record(gs)
+}
+
+func keyRange(lo, hi uint8) int16 {
+	return int16(uint16(hi)<<8 | uint16(lo))
+}
+
+// testFont has a single 440Hz sample, looped over whole cycles, in an instrument with a
+// quiet zone for soft notes and a loud one for hard notes, played by program 5
+func testFont() []byte {
+	const rate = 44000
+	smpl := make([]int16, rate/2)
+	for i := range smpl {
+		smpl[i] = int16(16000 * math.Sin(2*math.Pi*440*float64(i)/rate))
+	}
+	data := &bytes.Buffer{}
+	binary.Write(data, binary.LittleEndian, smpl)
+
+	phdr := bytes.Join([][]byte{
+		record("Test", uint16(5), uint16(0), uint16(0), uint32(0), uint32(0), uint32(0)),
+		record("EOP", uint16(0), uint16(0), uint16(1), uint32(0), uint32(0), uint32(0)),
+	}, nil)
+	pbag := record(uint16(0), uint16(0), uint16(1), uint16(0))
+	pgen := gens(gen{genInstrument, 0}, gen{})
+
+	inst := bytes.Join([][]byte{record("Sine", uint16(0)), record("EOI", uint16(3))}, nil)
+	ibag := record(uint16(0), uint16(0), uint16(2), uint16(0), uint16(6), uint16(0), uint16(9), uint16(0))
+	igen := gens(
+		// global zone
+		gen{genSustainVolEnv, 100}, gen{genSampleModes, 1},
+		// soft, 6dB quieter
+		gen{genKeyRange, keyRange(40, 100)}, gen{genVelRange, keyRange(0, 63)}, gen{genInitialAttenuation, 60}, gen{genSampleID, 0},
+		// hard
+		gen{genKeyRange, keyRange(40, 100)}, gen{genVelRange, keyRange(64, 127)}, gen{genSampleID, 0},
+		gen{},
+	)
+	shdr := bytes.Join([][]byte{
+		record("Sine", uint32(0), uint32(len(smpl)), uint32(1000), uint32(1000+100*200), uint32(rate), uint8(69), int8(0), uint16(0), uint16(1)),
+		record("EOS", uint32(0), uint32(0), uint32(0), uint32(0), uint32(0), uint8(0), int8(0), uint16(0), uint16(0)),
+	}, nil)
+
+	return riff("RIFF", []byte("sfbk"),
+		riff("LIST", []byte("INFO"), riff("ifil", []byte{2, 0, 1, 0})),
+		riff("LIST", []byte("sdta"), riff("smpl", data.Bytes())),
+		riff("LIST", []byte("pdta"),
+			riff("phdr", phdr), riff("pbag", pbag), riff("pmod", make([]byte, 10)), riff("pgen", pgen),
+			riff("inst", inst), riff("ibag", ibag), riff("imod", make([]byte, 10)), riff("igen", igen),
+			riff("shdr", shdr),
+		),
+	)
+}
+
+func TestNote(t *testing.T) {
+	sf, err := Parse(testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const sr = beep.SampleRate(48000)
+	peak := func(vel uint8, freq float64) (float64, float64) {
+		g, adsr, err := sf.Note(sr, 0, 5, 81, vel, freq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(adsr.Sustain-math.Pow(10, -0.5)) > 1e-6 || adsr.Attack > time.Millisecond {
+			t.Errorf("Expected the envelope of the global zone, got %+v", adsr)
+		}
+
+		// a second, longer than the sample so the loop is played
+		out := make([][2]float64, sr.N(time.Second))
+		g.Stream(out)
+
+		var max float64
+		crossings := 0
+		for i := 1; i < len(out); i++ {
+			max = math.Max(max, math.Abs(out[i][0]))
+			if out[i-1][0] < 0 && out[i][0] >= 0 {
+				crossings++
+			}
+		}
+		return max, float64(crossings)
+	}
+
+	loud, cycles := peak(100, 880)
+	if math.Abs(cycles-880) > 2 {
+		t.Errorf("Expected 880 cycles, got %v", cycles)
+	}
+
+	soft, _ := peak(30, 880)
+	if ratio := soft / loud; math.Abs(ratio-0.5) > 0.02 {
+		t.Errorf("Expected the soft zone to be 6dB quieter, got a ratio of %v", ratio)
+	}
+
+	if _, _, err := sf.Note(sr, 0, 5, 20, 100, 25.96); err == nil {
+		t.Error("Expected no zone for a key out of range")
+	}
+
+	if _, _, err := sf.Note(sr, 0, 6, 69, 100, 440); err == nil {
+		t.Error("Expected no preset for program 6")
+	}
+}
+
+// presets offset the tuning, the attenuation and the envelope, never where the sample and its loop are
+func TestPresetOffsets(t *testing.T) {
+	h := sampleHeader{start: 0, end: 22000, startLoop: 1000, endLoop: 21000}
+
+	izone, pzone := &zone{}, &zone{}
+	izone.gens[genStartloopAddrsOffset], izone.set[genStartloopAddrsOffset] = 10, true
+	for _, op := range []int{genStartAddrsOffset, genEndAddrsOffset, genStartloopAddrsOffset, genEndloopAddrsOffset, genStartloopCoarseOffset} {
+		pzone.gens[op], pzone.set[op] = 100, true
+	}
+	pzone.gens[genAttackVolEnv], pzone.set[genAttackVolEnv] = 1200, true
+
+	v := generatorValues{izone: izone, pzone: pzone}
+	start, end, loopStart, loopEnd := addresses(h, v)
+	if start != 0 || end != 22000 || loopStart != 1010 || loopEnd != 21000 {
+		t.Errorf("Expected the instrument's loop from 1010 to 21000 in 0 to 22000, got %d to %d in %d to %d", loopStart, loopEnd, start, end)
+	}
+
+	// an attack of 1 second doubled by the preset
+	izone.gens[genAttackVolEnv], izone.set[genAttackVolEnv] = 0, true
+	if attack := envelope(v).Attack; attack != 2*time.Second {
+		t.Errorf("Expected the preset to double the attack to 2s, got %v", attack)
+	}
+}
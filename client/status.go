@@ -0,0 +1,89 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// how often the server is told how the song is going
+const statusInterval = 2 * time.Second
+
+// a note that takes this much longer to arrive than the quickest one is late
+const lateThreshold = 20 * time.Millisecond
+
+// status counts what happened to the notes of the song, for the STATUS packets
+type status struct {
+	received, played, late, dropped uint32
+
+	// quickest is the shortest a note took to arrive in this report and the one before, measured
+	// between the clocks of the server and the client so it includes their offset. A note is late
+	// by however much longer than that it took.
+	quickest, prevQuickest time.Duration
+	timed, prevTimed       bool
+
+	// worst is the most any note of this report was late
+	worst time.Duration
+}
+
+// stats is the status of the song being played
+var stats status
+
+// arrived counts a note sent at the due timestamp that arrived at now and returns how late it is,
+// 0 when it has no timestamp
+func (s *status) arrived(due uint32, now time.Time) time.Duration {
+	s.received++
+	if due == 0 {
+		return 0
+	}
+
+	took := time.Duration(int32(shared.Timestamp(now)-due)) * time.Millisecond
+	if !s.timed || took < s.quickest {
+		s.quickest, s.timed = took, true
+	}
+
+	late := took - s.offset()
+	if late > s.worst {
+		s.worst = late
+	}
+	if late > lateThreshold {
+		s.late++
+	}
+
+	return late
+}
+
+// offset is the quickest a note took to arrive lately
+func (s *status) offset() time.Duration {
+	if s.prevTimed && (!s.timed || s.prevQuickest < s.quickest) {
+		return s.prevQuickest
+	}
+	return s.quickest
+}
+
+// report makes the STATUS packet and starts a new report
+func (s *status) report(underruns uint32) *shared.STATUS_Packet {
+	pkt := &shared.STATUS_Packet{
+		Received:  s.received,
+		Played:    s.played,
+		Late:      s.late,
+		Dropped:   s.dropped,
+		Underruns: underruns,
+		Offset:    s.offset(),
+	}
+
+	// without a timed note there is nothing to measure the slack of
+	if s.timed {
+		pkt.Slack, pkt.HasSlack = lateThreshold-s.worst, true
+	}
+
+	// the quickest time is kept for one more report, so the clocks drifting apart are followed
+	// without forgetting it all at once
+	if s.timed {
+		s.prevQuickest, s.prevTimed = s.quickest, true
+	}
+	s.timed = false
+	s.worst = 0
+
+	return pkt
+}
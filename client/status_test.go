@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+func TestStatus(t *testing.T) {
+	// the timestamps wrap around during the song
+	start := time.Unix(0, (1<<32-500)*int64(time.Millisecond))
+
+	tests := []struct {
+		name string
+		// how long each note took to arrive, measured between the clocks
+		took     []time.Duration
+		late     []time.Duration
+		expected shared.STATUS_Packet
+	}{
+		{
+			name:     "on time",
+			took:     []time.Duration{30 * time.Millisecond, 32 * time.Millisecond},
+			late:     []time.Duration{0, 2 * time.Millisecond},
+			expected: shared.STATUS_Packet{Received: 2, HasSlack: true, Slack: 18 * time.Millisecond, Offset: 30 * time.Millisecond},
+		},
+		{
+			name:     "late",
+			took:     []time.Duration{30 * time.Millisecond, 60 * time.Millisecond},
+			late:     []time.Duration{0, 30 * time.Millisecond},
+			expected: shared.STATUS_Packet{Received: 2, HasSlack: true, Late: 1, Slack: -10 * time.Millisecond, Offset: 30 * time.Millisecond},
+		},
+		{
+			name:     "quicker later",
+			took:     []time.Duration{50 * time.Millisecond, 40 * time.Millisecond},
+			late:     []time.Duration{0, 0},
+			expected: shared.STATUS_Packet{Received: 2, HasSlack: true, Slack: 20 * time.Millisecond, Offset: 40 * time.Millisecond},
+		},
+		{
+			name:     "server clock ahead",
+			took:     []time.Duration{-100 * time.Millisecond, -95 * time.Millisecond},
+			late:     []time.Duration{0, 5 * time.Millisecond},
+			expected: shared.STATUS_Packet{Received: 2, HasSlack: true, Slack: 15 * time.Millisecond, Offset: -100 * time.Millisecond},
+		},
+		{
+			name:     "just under the threshold",
+			took:     []time.Duration{10 * time.Millisecond, 30 * time.Millisecond},
+			late:     []time.Duration{0, 20 * time.Millisecond},
+			expected: shared.STATUS_Packet{Received: 2, HasSlack: true, Offset: 10 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		var s status
+		for i, took := range test.took {
+			due := start.Add(time.Duration(i) * time.Second)
+			if late := s.arrived(shared.Timestamp(due), due.Add(took)); late != test.late[i] {
+				t.Errorf("%s: expected note %d to be %v late, got %v", test.name, i, test.late[i], late)
+			}
+		}
+
+		if pkt := s.report(0); *pkt != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, *pkt)
+		}
+	}
+}
+
+func TestStatusUntimed(t *testing.T) {
+	var s status
+	if late := s.arrived(0, time.Now()); late != 0 {
+		t.Errorf("Expected a note without a timestamp to be on time, got %v late", late)
+	}
+
+	// without a timed note the slack wasn't measured
+	s.played++
+	expected := shared.STATUS_Packet{Received: 1, Played: 1, Underruns: 3}
+	if pkt := s.report(3); *pkt != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *pkt)
+	}
+}
+
+// the quickest note is remembered for one more report, so a slow report is measured against it
+func TestStatusOffsetAcrossReports(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var s status
+	s.arrived(shared.Timestamp(start), start.Add(30*time.Millisecond))
+	s.report(0)
+
+	late := s.arrived(shared.Timestamp(start), start.Add(55*time.Millisecond))
+	if late != 25*time.Millisecond {
+		t.Errorf("Expected to be measured against the last report, got %v late", late)
+	}
+	if pkt := s.report(0); pkt.Offset != 30*time.Millisecond || pkt.Late != 1 {
+		t.Errorf("Expected an offset of 30ms and 1 late note, got %v and %d", pkt.Offset, pkt.Late)
+	}
+
+	// the clocks drifted, by now the old offset is forgotten
+	late = s.arrived(shared.Timestamp(start), start.Add(55*time.Millisecond))
+	if late != 0 {
+		t.Errorf("Expected the drift to be followed, got %v late", late)
+	}
+	if pkt := s.report(0); pkt.Offset != 55*time.Millisecond {
+		t.Errorf("Expected an offset of 55ms, got %v", pkt.Offset)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// stealPolicy decides which note gives up its voice when every voice is busy
+type stealPolicy int
+
+const (
+	stealOldest   stealPolicy = iota // steal the note that started first
+	stealQuietest                    // steal the softest note, drop the new note if it is softer still
+	stealPriority                    // steal the note on the highest numbered track, drop the new note if its track is higher still. Live notes are ranked by channel.
+)
+
+func parseStealPolicy(s string) (stealPolicy, error) {
+	switch s {
+	case "oldest":
+		return stealOldest, nil
+	case "quietest":
+		return stealQuietest, nil
+	case "priority":
+		return stealPriority, nil
+	default:
+		return 0, fmt.Errorf("unknown steal policy %q (expected oldest, quietest or priority)", s)
+	}
+}
+
+// allocator hands out client voices to notes at the moment they start
+type allocator struct {
+	slots  []slot
+	policy stealPolicy
+
+	// number of playing notes cut short to make room for a new note
+	stolen int
+	// number of new notes that didn't get a voice at all
+	dropped int
+}
+
+// note is a single note as seen by the allocator
+type note struct {
+	track   int16
+	channel uint8
+	key     uint8
+	vel     uint8
+
+	start time.Duration
+	// end is unknown for live notes, they are held until noteOff
+	end time.Duration
+}
+
+// slot is a single voice on a single client
+type slot struct {
+	busy bool
+	note note
+
+	// when the last note was released, used to rotate through the free voices
+	released time.Duration
+}
+
+func newAllocator(n int, policy stealPolicy) *allocator {
+	if n == 0 {
+		panic("n must be > 0")
+	}
+
+	return &allocator{slots: make([]slot, n), policy: policy}
+}
+
+// expire releases every voice whose note has ended by now, only used when the note ends are known
+func (a *allocator) expire(now time.Duration) {
+	for i, s := range a.slots {
+		if s.busy && s.note.end <= now {
+			a.slots[i].busy = false
+			a.slots[i].released = s.note.end
+		}
+	}
+}
+
+// noteOn finds a voice for the note and returns its index.
+// If every voice is busy a note is stolen according to the policy, in which case prev is the
+// note that was playing on the returned voice and stolen is true.
+// Returns -1 if the policy decided to drop the new note instead.
+func (a *allocator) noteOn(n note) (i int, prev note, stolen bool) {
+	i = -1
+
+	// prefer the voice that has been free the longest
+	for j, s := range a.slots {
+		if !s.busy && (i == -1 || s.released < a.slots[i].released) {
+			i = j
+		}
+	}
+
+	if i == -1 {
+		i = a.victim()
+
+		victim := a.slots[i].note
+		switch {
+		case a.policy == stealQuietest && n.vel < victim.vel,
+			a.policy == stealPriority && n.track > victim.track:
+			a.dropped++
+			return -1, note{}, false
+		}
+
+		prev, stolen = victim, true
+		a.stolen++
+	}
+
+	a.slots[i] = slot{busy: true, note: n}
+
+	return i, prev, stolen
+}
+
+// victim picks the busy voice to steal from according to the policy
+func (a *allocator) victim() int {
+	v := 0
+	for j, s := range a.slots[1:] {
+		j++
+
+		var better bool
+		switch a.policy {
+		case stealOldest:
+			better = s.note.start < a.slots[v].note.start
+		case stealQuietest:
+			better = s.note.vel < a.slots[v].note.vel
+		case stealPriority:
+			better = s.note.track > a.slots[v].note.track
+		}
+
+		if better {
+			v = j
+		}
+	}
+
+	return v
+}
+
+// noteOff releases the voice playing key on channel and returns its index.
+// Returns -1 if the note isn't playing, for example because it was stolen.
+func (a *allocator) noteOff(channel, key uint8, now time.Duration) int {
+	for i, s := range a.slots {
+		if s.busy && s.note.channel == channel && s.note.key == key {
+			a.slots[i].busy = false
+			a.slots[i].released = now
+			return i
+		}
+	}
+
+	return -1
+}
+
+// allocate assigns every note of the song to one of n streams at the time the note starts.
+// Unlike merge no stream ever holds two notes at once, stolen notes are cut short instead.
+func allocate(voices []*voice, n int, policy stealPolicy) []stream {
+	fmt.Println("Allocating", len(voices), "voices onto", n, "streams")
+
+	events := flatten(voices)
+	a := newAllocator(n, policy)
+	streams := make([]stream, n)
+
+	for _, event := range events {
+		a.expire(event.rt)
+
+		i, _, stolen := a.noteOn(note{
+			track:   event.track,
+			channel: event.channel,
+			key:     event.key,
+			vel:     event.vel,
+			start:   event.rt,
+			end:     event.rt + event.dur,
+		})
+		if i == -1 {
+			continue
+		}
+
+		// cut the stolen note short so the stream stays monophonic
+		if stolen {
+			last := &streams[i].events[len(streams[i].events)-1]
+			streams[i].totalOnTime -= last.dur
+			last.dur = event.rt - last.rt
+			streams[i].totalOnTime += last.dur
+		}
+
+		streams[i].events = append(streams[i].events, event)
+		streams[i].totalOnTime += event.dur
+	}
+
+	fmt.Println("Stole", a.stolen, "notes and dropped", a.dropped, "notes out of", len(events))
+
+	return streams
+}
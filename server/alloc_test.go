@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllocatorSteal(t *testing.T) {
+	// Fill two voices then check each policy picks the expected victim
+	first := note{track: 2, key: 60, vel: 100, start: 0}
+	second := note{track: 1, key: 64, vel: 20, start: time.Second}
+
+	tests := []struct {
+		policy   stealPolicy
+		incoming note
+		voice    int
+		victim   uint8
+	}{
+		{stealOldest, note{track: 3, key: 67, vel: 10}, 0, first.key},
+		{stealQuietest, note{track: 3, key: 67, vel: 50}, 1, second.key},
+		{stealPriority, note{track: 0, key: 67, vel: 10}, 0, first.key},
+	}
+
+	for _, test := range tests {
+		a := newAllocator(2, test.policy)
+		a.noteOn(first)
+		a.noteOn(second)
+
+		i, prev, stolen := a.noteOn(test.incoming)
+		if !stolen {
+			t.Errorf("policy %d: expected a note to be stolen", test.policy)
+			continue
+		}
+
+		if i != test.voice || prev.key != test.victim {
+			t.Errorf("policy %d: expected to steal key %d from voice %d, got key %d from voice %d", test.policy, test.victim, test.voice, prev.key, i)
+		}
+	}
+}
+
+func TestAllocatorDrop(t *testing.T) {
+	a := newAllocator(1, stealQuietest)
+	a.noteOn(note{key: 60, vel: 100})
+
+	// a softer note shouldn't interrupt a louder one
+	i, _, _ := a.noteOn(note{key: 62, vel: 10})
+	if i != -1 {
+		t.Errorf("Expected the new note to be dropped, got voice %d", i)
+	}
+
+	if a.dropped != 1 || a.stolen != 0 {
+		t.Errorf("Expected 1 dropped and 0 stolen, got %d and %d", a.dropped, a.stolen)
+	}
+
+	// once the note is released the voice is free again
+	if a.noteOff(0, 60, time.Second) != 0 {
+		t.Error("Expected key 60 to be released from voice 0")
+	}
+
+	i, _, stolen := a.noteOn(note{key: 62, vel: 10})
+	if i != 0 || stolen {
+		t.Errorf("Expected voice 0 without stealing, got voice %d stolen %v", i, stolen)
+	}
+}
+
+func TestAllocatorPriority(t *testing.T) {
+	// live notes are ranked by their channel
+	a := newAllocator(2, stealPriority)
+	a.noteOn(note{track: 0, channel: 0, key: 60})
+	a.noteOn(note{track: 9, channel: 9, key: 64})
+
+	// the lowest priority note loses its voice, wherever it is
+	i, prev, stolen := a.noteOn(note{track: 1, channel: 1, key: 67})
+	if i != 1 || !stolen || prev.key != 64 {
+		t.Errorf("Expected to steal key 64 from voice 1, got key %d from voice %d", prev.key, i)
+	}
+
+	// a note of lower priority than every playing note is dropped
+	if i, _, _ := a.noteOn(note{track: 5, channel: 5, key: 72}); i != -1 || a.dropped != 1 {
+		t.Errorf("Expected the new note to be dropped, got voice %d", i)
+	}
+}
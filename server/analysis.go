@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// polyphony describes how much a set of notes overlaps
+type polyphony struct {
+	// most notes sounding at once
+	max int
+	// total time during which more than one note is sounding
+	overlap time.Duration
+}
+
+// analyze sweeps over the notes to find how many sound at once
+func analyze(events []streamEvent) polyphony {
+	l := overlaps(eventSpans(events))
+
+	var p polyphony
+	for i, n := range l.count {
+		if n > p.max {
+			p.max = n
+		}
+		if n > 1 && i+1 < len(l.at) {
+			p.overlap += l.at[i+1] - l.at[i]
+		}
+	}
+
+	return p
+}
+
+// span is the time a note sounds
+type span struct {
+	start, end time.Duration
+}
+
+func eventSpans(events []streamEvent) []span {
+	spans := make([]span, len(events))
+	for i, event := range events {
+		spans[i] = span{event.rt, event.rt + event.dur}
+	}
+	return spans
+}
+
+// levels is how many spans overlap from each of the times on until the next
+type levels struct {
+	at    []time.Duration
+	count []int
+}
+
+// overlaps sweeps over the starts and ends of the spans, counting how many overlap
+func overlaps(spans []span) levels {
+	type edge struct {
+		t     time.Duration
+		delta int
+	}
+
+	edges := make([]edge, 0, 2*len(spans))
+	for _, s := range spans {
+		edges = append(edges, edge{s.start, 1}, edge{s.end, -1})
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].t < edges[j].t })
+
+	var l levels
+	n := 0
+	for i, e := range edges {
+		n += e.delta
+		// a span ending as another starts doesn't overlap it
+		if i+1 < len(edges) && edges[i+1].t == e.t {
+			continue
+		}
+
+		l.at = append(l.at, e.t)
+		l.count = append(l.count, n)
+	}
+	return l
+}
+
+// most is the most spans overlapping at once from start until end
+func (l levels) most(start, end time.Duration) int {
+	i := sort.Search(len(l.at), func(i int) bool { return l.at[i] > start }) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	most := 0
+	for j := i; j < len(l.at) && (j == i || l.at[j] < end); j++ {
+		if l.count[j] > most {
+			most = l.count[j]
+		}
+	}
+	return most
+}
+
+// busy joins the spans into the times at least one of them sounds, spans that touch are joined too
+func (l levels) busy() []span {
+	var joined []span
+	open := false
+	for i, n := range l.count {
+		switch {
+		case n > 0 && !open:
+			joined = append(joined, span{start: l.at[i]})
+			open = true
+		case n == 0 && open:
+			joined[len(joined)-1].end = l.at[i]
+			open = false
+		}
+	}
+	return joined
+}
+
+// report prints the polyphony of every stream and of the song as a whole.
+// Each client plays one note at a time, so the song's polyphony is the number of clients
+// needed to play it without any overlaps, plus the drum clients' own when they have them.
+func report(voices []*voice, streams []stream, drums drumRouting) {
+	for i, stream := range streams {
+		p := analyze(stream.events)
+		fmt.Printf("Stream %d: max polyphony %d, overlap %v\n", i, p.max, p.overlap)
+	}
+
+	p, kit := analyzeRouted(voices, drums)
+	if drums.dedicated > 0 {
+		fmt.Printf("Drums: max polyphony %d, overlap %v\n", kit.max, kit.overlap)
+	}
+	fmt.Printf("Song: max polyphony %d, overlap %v, %d clients needed for zero overlaps\n", p.max, p.overlap, p.max+kit.max)
+}
+
+// analyzeRouted analyzes the notes the way the drums are routed: dropped drums aren't played and
+// dedicated drum clients play the drums apart from the rest, kit is empty unless they do
+func analyzeRouted(voices []*voice, drums drumRouting) (song, kit polyphony) {
+	if !drums.drop && drums.dedicated == 0 {
+		return analyze(flatten(voices)), kit
+	}
+
+	pitched, percussion := splitDrums(voices)
+	if drums.dedicated > 0 {
+		kit = analyze(flatten(percussion))
+	}
+	return analyze(flatten(pitched)), kit
+}
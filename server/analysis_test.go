@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	// a chord of two notes followed by a single note that starts exactly as the chord ends
+	events := []streamEvent{
+		{key: 60, rt: 0, dur: time.Second},
+		{key: 64, rt: time.Second / 2, dur: time.Second / 2},
+		{key: 67, rt: time.Second, dur: time.Second},
+	}
+
+	p := analyze(events)
+	if p.max != 2 {
+		t.Errorf("Expected max polyphony 2, got %d", p.max)
+	}
+
+	if p.overlap != time.Second/2 {
+		t.Errorf("Expected overlap %v, got %v", time.Second/2, p.overlap)
+	}
+}
+
+func TestBusy(t *testing.T) {
+	// two overlapping notes, one touching them and one after a gap
+	spans := []span{{0, 2 * time.Second}, {time.Second, 3 * time.Second}, {3 * time.Second, 4 * time.Second}, {5 * time.Second, 6 * time.Second}}
+
+	expected := []span{{0, 4 * time.Second}, {5 * time.Second, 6 * time.Second}}
+	got := overlaps(spans).busy()
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestAnalyzeRouted(t *testing.T) {
+	// three notes at once, and two drums hit together with them
+	voices := partitionVoices()
+	for _, key := range []uint8{36, 38} {
+		voices = append(voices, &voice{
+			channel: percussionChannel,
+			key:     key,
+			events: []voiceEvent{
+				{rt: time.Second, isOn: true, vel: 100},
+				{rt: 2 * time.Second, isOn: false},
+			},
+			totalOnTime: time.Second,
+		})
+	}
+
+	tests := []struct {
+		drums     drumRouting
+		song, kit int
+	}{
+		{drumRouting{}, 5, 0},
+		{drumRouting{drop: true}, 3, 0},
+		{drumRouting{dedicated: 1}, 3, 2},
+	}
+
+	for _, test := range tests {
+		song, kit := analyzeRouted(voices, test.drums)
+		if song.max != test.song || kit.max != test.kit {
+			t.Errorf("%+v: expected polyphony %d and %d for the drums, got %d and %d", test.drums, test.song, test.kit, song.max, kit.max)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// calibrate clicks on every client once a second. Each click is sent early by the client's
+// compensation, so when the offsets in the room file are right the clicks sound as one.
+// The click is played with the given timbre, short bursts of noise are the easiest to line up by ear.
+// Never returns, stop it with an interrupt.
+func calibrate(clients []*client, send chan<- shared.Message, timbre string) {
+	clicks := make([]*shared.PLAY_Packet, len(clients))
+	for i, c := range clients {
+		fmt.Println("Client", c, "compensated by", c.compensation())
+
+		clicks[i] = &shared.PLAY_Packet{
+			Duration:  time.Millisecond * 20,
+			Frequency: 1000,
+			Amplitude: 0.5,
+			Voice:     c.voice(timbre),
+		}
+	}
+
+	for beat := time.Now().Add(lead(clients) + time.Second); ; beat = beat.Add(time.Second) {
+		for i, c := range clients {
+			c, click := c, clicks[i]
+			time.AfterFunc(time.Until(beat.Add(-c.compensation())), func() {
+				send <- shared.Message{
+					Pkt:  click,
+					Addr: c.addr,
+				}
+			})
+		}
+
+		time.Sleep(time.Until(beat))
+	}
+}
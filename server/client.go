@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// client is a machine that answered discovery, recognized by its identity
+type client struct {
+	addr     *net.UDPAddr
+	identity [24]byte
+
+	// human readable name shown in the output
+	nickname string
+
+	// output latency reported by the client, guarded by statusMu once the song plays as the
+	// client may report it again. hasLatency is false until the first report arrives.
+	latency    time.Duration
+	hasLatency bool
+	// manual correction from the room file
+	offset time.Duration
+	// where the client sits between the left (-1) and right (1) of the room
+	pan float32
+
+	// voice ids of the timbres the client advertised, by name
+	timbres map[string]uint32
+	// how many notes the client plays at once according to its CAPS, 0 for no limit
+	voices int
+
+	// what the client reported and what it was sent while playing, guarded by statusMu
+	// clips is the times its output went over full scale, seen is when it was last heard from,
+	// reports counts its STATUS packets
+	clips   int
+	status  shared.STATUS_Packet
+	reports int
+	seen    time.Time
+	rtt     time.Duration
+	sent    int
+}
+
+// newClient names the client after its seat in the room, the name in its identity or
+// the start of its identity, in that order. The room may be nil.
+func newClient(addr *net.UDPAddr, identity [24]byte, r *room) *client {
+	c := &client{addr: addr, identity: identity, timbres: make(map[string]uint32)}
+
+	if len(r.matches(c)) > 1 {
+		fmt.Println("Client", hex.EncodeToString(identity[:4]), "matches more than one seat in the room and has none")
+	}
+
+	s, ok := r.find(c)
+	if ok {
+		c.offset = time.Duration(s.Offset * float64(time.Millisecond))
+	}
+
+	if ok && s.Nickname != "" {
+		c.nickname = s.Nickname
+	} else if name, ok := shared.IdentityName(identity); ok {
+		c.nickname = name
+	} else {
+		c.nickname = hex.EncodeToString(identity[:4])
+	}
+
+	return c
+}
+
+// byAddr finds the client sending from addr, or nil
+func byAddr(clients []*client, addr *net.UDPAddr) *client {
+	for _, c := range clients {
+		if c.addr.String() == addr.String() {
+			return c
+		}
+	}
+	return nil
+}
+
+// midiKeys is the number of keys MIDI has
+const midiKeys = 128
+
+// polyphony is how many notes the client can hold at once, a client without a limit can hold every key
+func (c *client) polyphony() int {
+	if c.voices <= 0 || c.voices > midiKeys {
+		return midiKeys
+	}
+	return c.voices
+}
+
+// voice finds the voice id the client uses for a timbre.
+// Clients that didn't advertise the timbre get the sawtooth, which every client has, checkTimbre
+// says which ones.
+func (c *client) voice(timbre string) uint32 {
+	if voice, ok := c.timbres[timbre]; ok {
+		return voice
+	}
+	return 1
+}
+
+// checkTimbre names the clients that didn't advertise the timbre and will play the sawtooth instead
+func checkTimbre(clients []*client, timbre string) {
+	for _, c := range clients {
+		if _, ok := c.timbres[timbre]; !ok {
+			fmt.Println("Client", c, "has no timbre", timbre, "and plays sawtooth instead")
+		}
+	}
+}
+
+// compensation is how much earlier than the rest this client should be sent its notes
+func (c *client) compensation() time.Duration {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return c.latency + c.offset
+}
+
+// lead is the largest compensation of all the clients
+func lead(clients []*client) time.Duration {
+	var l time.Duration
+	for _, c := range clients {
+		if c.compensation() > l {
+			l = c.compensation()
+		}
+	}
+
+	return l
+}
+
+func (c *client) String() string {
+	return c.nickname + " (" + c.addr.String() + ")"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MIDI controllers that change how notes are played
+const (
+	ccVolume     = 7
+	ccPan        = 10
+	ccExpression = 11
+	ccSustain    = 64
+)
+
+// ccDefaults are the values of the controllers before the song sets them
+var ccDefaults = map[uint8]uint8{
+	ccVolume:     100,
+	ccPan:        64,
+	ccExpression: 127,
+	ccSustain:    0,
+}
+
+type ccChange struct {
+	rt    time.Duration
+	value uint8
+}
+
+// controllers follows the controllers of every channel through the song
+type controllers struct {
+	// changes by channel and controller, sorted by time once the song is read
+	changes map[uint8]map[uint8][]ccChange
+
+	// current keeps only the last change of every controller, for live input where the notes only
+	// ever ask about now and the history would grow for as long as the performance lasts
+	current bool
+
+	// end is when the last note of the song ends, a pedal that is never let go holds its notes until then
+	end time.Duration
+}
+
+func newControllers() *controllers {
+	return &controllers{changes: make(map[uint8]map[uint8][]ccChange)}
+}
+
+func newLiveControllers() *controllers {
+	c := newControllers()
+	c.current = true
+	return c
+}
+
+func (c *controllers) add(channel, controller, value uint8, rt time.Duration) {
+	if _, ok := ccDefaults[controller]; !ok {
+		return
+	}
+
+	if c.changes[channel] == nil {
+		c.changes[channel] = make(map[uint8][]ccChange)
+	}
+
+	if c.current {
+		c.changes[channel][controller] = append(c.changes[channel][controller][:0], ccChange{rt, value})
+		return
+	}
+
+	c.changes[channel][controller] = append(c.changes[channel][controller], ccChange{rt, value})
+}
+
+// sort puts the changes in time order, tracks are read one after the other so they can arrive out of order
+func (c *controllers) sort() {
+	for _, channel := range c.changes {
+		for _, changes := range channel {
+			sort.SliceStable(changes, func(i, j int) bool {
+				return changes[i].rt < changes[j].rt
+			})
+		}
+	}
+}
+
+// at is the value of the controller at rt
+func (c *controllers) at(channel, controller uint8, rt time.Duration) uint8 {
+	value := ccDefaults[controller]
+	if c == nil {
+		return value
+	}
+
+	for _, change := range c.changes[channel][controller] {
+		if change.rt > rt {
+			break
+		}
+		value = change.value
+	}
+
+	return value
+}
+
+// gain folds volume and expression into an amplitude factor, both follow the squared curve of General MIDI.
+// It is 1 at the default volume so songs that never set it sound the same, and up to 1.6 at full volume.
+func (c *controllers) gain(channel uint8, rt time.Duration) float32 {
+	volume := float64(c.at(channel, ccVolume, rt)) / float64(ccDefaults[ccVolume])
+	expression := float64(c.at(channel, ccExpression, rt)) / 127
+	return float32(volume * volume * expression * expression)
+}
+
+// pan is the position of the channel between -1 (left) and 1 (right)
+func (c *controllers) pan(channel uint8, rt time.Duration) float32 {
+	return ccPanPosition(c.at(channel, ccPan, rt))
+}
+
+func ccPanPosition(value uint8) float32 {
+	return float32(math.Max(-1, (float64(value)-64)/63))
+}
+
+// sustainEnd is when a note released at rt stops sounding, which is later than rt while the
+// sustain pedal is down, up to the end of the song
+func (c *controllers) sustainEnd(channel uint8, rt time.Duration) time.Duration {
+	if c == nil || c.at(channel, ccSustain, rt) < 64 {
+		return rt
+	}
+
+	for _, change := range c.changes[channel][ccSustain] {
+		if change.rt > rt && change.value < 64 {
+			return change.rt
+		}
+	}
+
+	// the pedal is never let go
+	if c.end > rt {
+		return c.end
+	}
+	return rt
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestControllers(t *testing.T) {
+	cc := newControllers()
+
+	// a song that never touches the controllers plays as before
+	if g := cc.gain(0, time.Second); g != 1 {
+		t.Errorf("Expected default gain 1, got %f", g)
+	}
+	if p := cc.pan(0, time.Second); p != 0 {
+		t.Errorf("Expected default pan 0, got %f", p)
+	}
+
+	// added out of order, as tracks are read one after the other
+	cc.add(0, ccPan, 127, 2*time.Second)
+	cc.add(0, ccPan, 0, time.Second)
+	cc.add(0, ccExpression, 0, 3*time.Second)
+	cc.sort()
+
+	if p := cc.pan(0, 1500*time.Millisecond); p != -1 {
+		t.Errorf("Expected pan -1, got %f", p)
+	}
+	if p := cc.pan(0, 2*time.Second); p != 1 {
+		t.Errorf("Expected pan 1, got %f", p)
+	}
+	if g := cc.gain(0, 3*time.Second); g != 0 {
+		t.Errorf("Expected silence without expression, got %f", g)
+	}
+	if g := cc.gain(1, 3*time.Second); g != 1 {
+		t.Errorf("Expected other channels to keep gain 1, got %f", g)
+	}
+}
+
+func TestSustainEnd(t *testing.T) {
+	cc := newControllers()
+	cc.add(0, ccSustain, 127, time.Second)
+	cc.add(0, ccSustain, 0, 3*time.Second)
+	cc.add(1, ccSustain, 127, time.Second)
+	cc.sort()
+	cc.end = 10 * time.Second
+
+	tests := []struct {
+		channel uint8
+		rt, end time.Duration
+	}{
+		// released before the pedal goes down
+		{0, 500 * time.Millisecond, 500 * time.Millisecond},
+		// held until the pedal comes up
+		{0, 2 * time.Second, 3 * time.Second},
+		// released after the pedal comes up
+		{0, 4 * time.Second, 4 * time.Second},
+		// the pedal is never let go, the note is held to the end of the song
+		{1, 2 * time.Second, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		if end := cc.sustainEnd(test.channel, test.rt); end != test.end {
+			t.Errorf("Channel %d released at %v: expected %v, got %v", test.channel, test.rt, test.end, end)
+		}
+	}
+}
+
+func TestLiveControllers(t *testing.T) {
+	cc := newLiveControllers()
+	for i := 0; i < 1000; i++ {
+		cc.add(0, ccExpression, uint8(i%128), time.Duration(i)*time.Millisecond)
+	}
+
+	// only the latest value is kept
+	if n := len(cc.changes[0][ccExpression]); n != 1 {
+		t.Errorf("Expected 1 change to be kept, got %d", n)
+	}
+	if v := cc.at(0, ccExpression, time.Second); v != 999%128 {
+		t.Errorf("Expected the latest expression %d, got %d", 999%128, v)
+	}
+}
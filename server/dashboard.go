@@ -0,0 +1,440 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// screen is the terminal while a dashboard has it, state is nil when it doesn't.
+// The lines logged meanwhile are kept to be shown on the dashboard and printed once it is gone.
+var screen struct {
+	sync.Mutex
+	state *terminal.State
+	lines []string
+}
+
+// the most logged lines kept while the dashboard has the screen
+const maxLogLines = 200
+
+// logf prints a line, or hands it to the dashboard while it has the screen
+func logf(format string, a ...interface{}) {
+	line := fmt.Sprintf(format, a...)
+
+	screen.Lock()
+	defer screen.Unlock()
+
+	if screen.state == nil {
+		fmt.Println(line)
+		return
+	}
+
+	screen.lines = append(screen.lines, line)
+	if len(screen.lines) > maxLogLines {
+		screen.lines = screen.lines[len(screen.lines)-maxLogLines:]
+	}
+}
+
+// enterScreen switches the terminal to raw mode and to its alternate screen
+func enterScreen() error {
+	screen.Lock()
+	defer screen.Unlock()
+
+	state, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+
+	screen.state = state
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+// leaveScreen puts the terminal back the way it was and prints what was logged meanwhile,
+// it does nothing when the dashboard doesn't have the screen
+func leaveScreen() {
+	screen.Lock()
+	defer screen.Unlock()
+
+	if screen.state == nil {
+		return
+	}
+
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	terminal.Restore(int(os.Stdin.Fd()), screen.state)
+	screen.state = nil
+
+	for _, line := range screen.lines {
+		fmt.Println(line)
+	}
+	screen.lines = nil
+}
+
+// keys are read from the terminal once for every dashboard, a read can't be interrupted
+var (
+	keysOnce sync.Once
+	keys     = make(chan string, 16)
+)
+
+// the arrow keys as the terminal sends them
+const (
+	keyRight = "\x1b[C"
+	keyLeft  = "\x1b[D"
+	keyCtrlC = "\x03"
+)
+
+func readKeys() {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, k := range splitKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// splitKeys splits what was read from the terminal into keys, the arrows come as escape sequences
+func splitKeys(b []byte) []string {
+	var ks []string
+	for len(b) > 0 {
+		n := 1
+		if len(b) >= 3 && b[0] == 0x1b && b[1] == '[' {
+			n = 3
+		}
+
+		ks = append(ks, string(b[:n]))
+		b = b[n:]
+	}
+	return ks
+}
+
+// how often the dashboard is drawn, and how often the position is logged instead without a terminal
+const (
+	drawInterval  = 200 * time.Millisecond
+	plainInterval = 5 * time.Second
+)
+
+// how far the keys move the song and change its tempo
+const (
+	seekStep  = 5 * time.Second
+	tempoStep = 0.05
+)
+
+// a client that wasn't heard from for this long is shown as silent
+const silentAfter = 5 * time.Second
+
+// the number of upcoming notes shown
+const upcomingNotes = 5
+
+// dashboard shows the song and the clients while playing. On a terminal it takes the whole screen
+// and the keys control the transport, otherwise the position is logged now and then.
+type dashboard struct {
+	song *song
+	// index is the position of the song in the playlist of count songs
+	index, count int
+
+	clients []*client
+	tr      *transport
+}
+
+// run shows the dashboard until stop is closed
+func (d *dashboard) run(stop <-chan struct{}) {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		d.plain(stop)
+		return
+	}
+
+	if err := enterScreen(); err != nil {
+		fmt.Println(err)
+		d.plain(stop)
+		return
+	}
+	defer leaveScreen()
+
+	keysOnce.Do(func() { go readKeys() })
+
+	// forget the keys pressed between songs
+	for len(keys) > 0 {
+		<-keys
+	}
+
+	ticker := time.NewTicker(drawInterval)
+	defer ticker.Stop()
+
+	for {
+		d.draw()
+
+		select {
+		case <-stop:
+			return
+		case k := <-keys:
+			d.key(k)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *dashboard) key(k string) {
+	switch k {
+	case " ", "p":
+		d.tr.togglePause()
+	case keyLeft, ",":
+		d.tr.seek(-seekStep)
+	case keyRight, ".":
+		d.tr.seek(seekStep)
+	case "+", "=":
+		d.tr.setTempo(tempoStep)
+	case "-", "_":
+		d.tr.setTempo(-tempoStep)
+	case "0":
+		d.tr.resetTempo()
+	case "n":
+		d.tr.stop(false)
+	case "q", keyCtrlC:
+		d.tr.stop(true)
+	}
+}
+
+// plain logs the position and the clients that went silent, for when there is no terminal to draw on
+func (d *dashboard) plain(stop <-chan struct{}) {
+	ticker := time.NewTicker(plainInterval)
+	defer ticker.Stop()
+
+	silent := make(map[*client]bool)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		logf("%s", d.header())
+
+		statusMu.Lock()
+		for _, c := range d.clients {
+			quiet := !c.seen.IsZero() && time.Since(c.seen) > silentAfter
+			if quiet && !silent[c] {
+				logf("Client %v has gone silent", c)
+			} else if !quiet && silent[c] {
+				logf("Client %v is back", c)
+			}
+			silent[c] = quiet
+		}
+		statusMu.Unlock()
+	}
+}
+
+// header is the song, where it is and how it is playing
+func (d *dashboard) header() string {
+	c := d.tr.now()
+	pos := c.position(time.Now())
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > d.song.duration {
+		pos = d.song.duration
+	}
+
+	state := "playing"
+	if c.paused {
+		state = "paused"
+	}
+
+	return fmt.Sprintf("Song %d/%d %s: %s / %s, %s at %.0f%% tempo",
+		d.index+1, d.count, d.song.name, formatPosition(pos), formatPosition(d.song.duration), state, c.tempo*100)
+}
+
+func (d *dashboard) draw() {
+	// some terminals don't know their size
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	lines := d.lines(width, height)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if r := []rune(line); len(r) > width {
+			line = string(r[:width])
+		}
+
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+
+		// raw mode needs the carriage return
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\x1b[J")
+
+	os.Stdout.WriteString(b.String())
+}
+
+// lines lays out the dashboard for a screen of the given size
+func (d *dashboard) lines(width, height int) []string {
+	c := d.tr.now()
+	pos := c.position(time.Now())
+
+	var lines []string
+	lines = append(lines, d.header())
+
+	// the progress bar, trimmed so the brackets fit
+	progress := 0.0
+	if d.song.duration > 0 {
+		progress = float64(pos) / float64(d.song.duration)
+	}
+	progress = clamp(progress, 0, 1)
+
+	bar := width - 2
+	if bar < 0 {
+		bar = 0
+	}
+	done := int(progress * float64(bar))
+	lines = append(lines, "["+strings.Repeat("=", done)+strings.Repeat(" ", bar-done)+"]", "")
+
+	// the clients get what is left after the table header, the upcoming notes, a few lines of the
+	// log and the keys, each section with its title and a blank line before it
+	rows := height - len(lines) - 1 - (2 + upcomingNotes) - (2 + 3) - 1
+	if rows < 1 {
+		rows = 1
+	}
+
+	lines = append(lines, fmt.Sprintf("%-20s %-8s %-21s %-8s %7s %8s %6s %6s %5s %5s %5s",
+		"Client", "Identity", "Address", "Seen", "RTT", "Offset", "Sent", "Played", "Late", "Drop", "Clips"))
+
+	// a client playing a stream on each speaker is listed once
+	var clients []*client
+	listed := make(map[*client]bool)
+	for _, cl := range d.clients {
+		if !listed[cl] {
+			clients = append(clients, cl)
+			listed[cl] = true
+		}
+	}
+
+	statusMu.Lock()
+	for i, cl := range clients {
+		if i == rows-1 && len(clients) > rows {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(clients)-i))
+			break
+		}
+
+		seen := "never"
+		if !cl.seen.IsZero() {
+			seen = "ok"
+			if since := time.Since(cl.seen); since > silentAfter {
+				seen = fmt.Sprintf("%.0fs ago", since.Seconds())
+			}
+		}
+
+		rtt := "-"
+		if cl.rtt > 0 {
+			rtt = fmt.Sprintf("%.1fms", float64(cl.rtt)/float64(time.Millisecond))
+		}
+
+		lines = append(lines, fmt.Sprintf("%-20s %-8s %-21s %-8s %7s %+6dms %6d %6d %5d %5d %5d",
+			cl.nickname, hex.EncodeToString(cl.identity[:4]), cl.addr, seen, rtt, cl.status.Offset.Milliseconds(),
+			cl.sent, cl.status.Played, cl.status.Late, cl.status.Dropped, cl.clips))
+	}
+	statusMu.Unlock()
+
+	lines = append(lines, "", "Upcoming")
+	for _, u := range d.upcoming(pos) {
+		name := noteName(u.event.key)
+		if u.event.channel == percussionChannel {
+			name = fmt.Sprintf("drum %d", u.event.key)
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %-8s ch %-2d -> %s", formatPosition(u.event.rt), name, u.event.channel+1, u.client.nickname))
+	}
+
+	// the newest lines of the log fill what is left above the keys
+	lines = append(lines, "", "Log")
+	room := height - len(lines) - 2
+	screen.Lock()
+	logged := screen.lines
+	if room < 0 {
+		room = 0
+	}
+	if len(logged) > room {
+		logged = logged[len(logged)-room:]
+	}
+	for _, line := range logged {
+		lines = append(lines, "  "+line)
+	}
+	screen.Unlock()
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "space pause  ←/→ seek 5s  +/- tempo  0 song tempo  n next song  q quit")
+
+	return lines
+}
+
+// upcomingNote is a note that is about to be sent and the client it goes to
+type upcomingNote struct {
+	event  streamEvent
+	client *client
+}
+
+// upcoming finds the next notes of the song after pos
+func (d *dashboard) upcoming(pos time.Duration) []upcomingNote {
+	var next []upcomingNote
+	for i, s := range d.song.streams {
+		if i >= len(d.clients) {
+			break
+		}
+
+		j := sort.Search(len(s.events), func(k int) bool { return s.events[k].rt >= pos })
+		for k := j; k < len(s.events) && k < j+upcomingNotes; k++ {
+			next = append(next, upcomingNote{s.events[k], d.clients[i]})
+		}
+	}
+
+	sort.Slice(next, func(i, j int) bool {
+		return next[i].event.rt < next[j].event.rt
+	})
+
+	if len(next) > upcomingNotes {
+		next = next[:upcomingNotes]
+	}
+	return next
+}
+
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// noteName names a MIDI key like C4 for middle C
+func noteName(key uint8) string {
+	return fmt.Sprintf("%s%d", noteNames[key%12], int(key)/12-1)
+}
+
+// formatPosition formats a position in the song as minutes and seconds
+func formatPosition(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%d:%04.1f", int(d/time.Minute), (d % time.Minute).Seconds())
+}
+
+func clamp(x, min, max float64) float64 {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitKeys(t *testing.T) {
+	keys := splitKeys([]byte(" \x1b[D+\x1b[Cq"))
+	expected := []string{" ", keyLeft, "+", keyRight, "q"}
+
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %q, got %q", expected, keys)
+	}
+}
+
+func TestDashboardFormat(t *testing.T) {
+	if name := noteName(60); name != "C4" {
+		t.Errorf("Expected middle C to be C4, got %s", name)
+	}
+	if name := noteName(70); name != "A#4" {
+		t.Errorf("Expected A#4, got %s", name)
+	}
+
+	if pos := formatPosition(83*time.Second + 400*time.Millisecond); pos != "1:23.4" {
+		t.Errorf("Expected 1:23.4, got %s", pos)
+	}
+}
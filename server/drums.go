@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// percussionChannel is channel 10 in General MIDI, its keys pick drums instead of pitches
+const percussionChannel = 9
+
+// drumRouting decides which clients play the percussion channel
+type drumRouting struct {
+	drop bool
+	// dedicated is how many clients play nothing but drums, 0 mixes the drums in with the other parts
+	dedicated int
+}
+
+func parseDrumRouting(s string) (drumRouting, error) {
+	switch s {
+	case "mix":
+		return drumRouting{}, nil
+	case "drop":
+		return drumRouting{drop: true}, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return drumRouting{}, fmt.Errorf("unknown drum routing %q (expected mix, drop or a number of clients)", s)
+	}
+
+	return drumRouting{dedicated: n}, nil
+}
+
+// splitDrums separates the voices of the percussion channel from the pitched ones
+func splitDrums(voices []*voice) (pitched, drums []*voice) {
+	for _, voice := range voices {
+		if voice.channel == percussionChannel {
+			drums = append(drums, voice)
+		} else {
+			pitched = append(pitched, voice)
+		}
+	}
+
+	return pitched, drums
+}
+
+// clients splits n clients into the ones playing pitched notes and the ones playing drums.
+// With dedicated drum clients they come last, so they are the last seats of the room.
+func (d drumRouting) clients(n int) (pitched, drums int, err error) {
+	switch {
+	case d.drop:
+		return n, 0, nil
+	case d.dedicated == 0:
+		return n, n, nil
+	case d.dedicated >= n:
+		return 0, 0, fmt.Errorf("can't give %d clients to the drums with only %d clients", d.dedicated, n)
+	default:
+		return n - d.dedicated, d.dedicated, nil
+	}
+}
+
+// partition splits the song into n streams, keeping the drums apart when they have dedicated clients
+func (d drumRouting) partition(p partitioner, voices []*voice, n int) ([]stream, error) {
+	pitchedN, drumsN, err := d.clients(n)
+	if err != nil {
+		return nil, err
+	}
+
+	pitched, drums := splitDrums(voices)
+	switch {
+	case d.drop:
+		if len(drums) > 0 {
+			fmt.Println("Dropping", len(drums), "drums")
+		}
+		return p.partition(pitched, n), nil
+	case d.dedicated == 0:
+		return p.partition(voices, n), nil
+	}
+
+	fmt.Println("Giving", len(drums), "drums to", drumsN, "clients")
+	return append(p.partition(pitched, pitchedN), p.partition(drums, drumsN)...), nil
+}
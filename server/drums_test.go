@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrumRouting(t *testing.T) {
+	note := func(channel, key uint8) *voice {
+		return &voice{
+			channel: channel,
+			key:     key,
+			events: []voiceEvent{
+				{rt: 0, isOn: true, vel: 100},
+				{rt: time.Second, isOn: false},
+			},
+			totalOnTime: time.Second,
+		}
+	}
+
+	voices := []*voice{note(0, 60), note(0, 64), note(1, 67), note(percussionChannel, 36), note(percussionChannel, 42)}
+
+	// the last client only plays drums
+	d, err := parseDrumRouting("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams, err := d.partition(fairTime{}, voices, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != 3 {
+		t.Fatalf("Expected 3 streams, got %d", len(streams))
+	}
+
+	for i, stream := range streams {
+		for _, event := range stream.events {
+			if drum := event.channel == percussionChannel; drum != (i == 2) {
+				t.Errorf("Stream %d got key %d on channel %d", i, event.key, event.channel)
+			}
+		}
+	}
+
+	if len(streams[2].events) != 2 {
+		t.Errorf("Expected both drums on the last stream, got %d events", len(streams[2].events))
+	}
+
+	// dropping leaves only the pitched notes
+	d, _ = parseDrumRouting("drop")
+	streams, _ = d.partition(fairTime{}, voices, 2)
+	total := 0
+	for _, stream := range streams {
+		total += len(stream.events)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 pitched notes, got %d", total)
+	}
+
+	// every client can't be a drummer
+	d, _ = parseDrumRouting("3")
+	if _, err := d.partition(fairTime{}, voices, 3); err == nil {
+		t.Error("Expected an error with no clients left for the pitched notes")
+	}
+}
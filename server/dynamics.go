@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// velocityCurve maps every MIDI velocity to an amplitude between 0 and 1
+type velocityCurve [128]float32
+
+// the quietest note of the exponential curve is this many decibels below the loudest
+const expCurveRange = 40
+
+// parseVelocityCurve parses linear, sqrt, exp or a table of amplitudes like "0,0.2,0.6,1" spread
+// evenly over the velocities and interpolated between.
+func parseVelocityCurve(s string) (*velocityCurve, error) {
+	var f func(x float64) float64
+	switch s {
+	case "linear":
+		f = func(x float64) float64 { return x }
+	case "sqrt":
+		f = math.Sqrt
+	case "exp":
+		f = func(x float64) float64 {
+			if x == 0 {
+				return 0
+			}
+			return math.Pow(10, (x-1)*expCurveRange/20)
+		}
+	default:
+		table, err := parseCurveTable(s)
+		if err != nil {
+			return nil, err
+		}
+
+		f = func(x float64) float64 {
+			pos := x * float64(len(table)-1)
+			i := int(pos)
+			if i == len(table)-1 {
+				return table[i]
+			}
+			return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+		}
+	}
+
+	var c velocityCurve
+	for vel := range c {
+		c[vel] = float32(f(float64(vel) / 127))
+	}
+
+	return &c, nil
+}
+
+func parseCurveTable(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("unknown velocity curve %q (expected linear, sqrt, exp or a table like 0,0.5,1)", s)
+	}
+
+	table := make([]float64, len(parts))
+	for i, part := range parts {
+		a, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("velocity curve %q: %w", s, err)
+		}
+		if a < 0 || a > 1 {
+			return nil, fmt.Errorf("velocity curve %q: amplitude %v is not between 0 and 1", s, a)
+		}
+		table[i] = a
+	}
+
+	return table, nil
+}
+
+// parseTrackGains parses gains for some tracks like "1=0.5,3=1.5", the other tracks keep a gain of 1
+func parseTrackGains(s string) (map[int16]float32, error) {
+	gains := make(map[int16]float32)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("track gain %q must be track=gain", part)
+		}
+
+		track, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("track gain %q: %w", part, err)
+		}
+
+		gain, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 32)
+		if err != nil {
+			return nil, fmt.Errorf("track gain %q: %w", part, err)
+		}
+		if gain < 0 {
+			return nil, fmt.Errorf("track gain %q must not be negative", part)
+		}
+
+		gains[int16(track)] = float32(gain)
+	}
+
+	return gains, nil
+}
+
+// dynamics decides how loud every note is played
+type dynamics struct {
+	curve *velocityCurve
+	// gain of the tracks that don't play at the same level as the rest
+	trackGains map[int16]float32
+
+	// normalize scales notes down by how many sound at once on their client and in the room,
+	// instead of leaving the fixed headroom for a couple of notes the clients always had
+	normalize bool
+}
+
+// the fixed headroom when not normalizing
+const headroom = 0.5
+
+// amplitude is how loud the client plays the note
+func (d *dynamics) amplitude(event streamEvent) float32 {
+	a := d.curve[event.vel&0x7F] * event.gain
+	if gain, ok := d.trackGains[event.track]; ok {
+		a *= gain
+	}
+
+	if !d.normalize {
+		return a * headroom
+	}
+
+	// the notes of a client add up, so sharing its output keeps a chord from clipping while
+	// a note on its own is played at full level
+	if event.sounding > 1 {
+		a /= float32(event.sounding)
+	}
+
+	// every client playing adds to what the room hears, the square root as they aren't in phase
+	if event.clients > 1 {
+		a /= float32(math.Sqrt(float64(event.clients)))
+	}
+
+	return float32(math.Min(float64(a), 1))
+}
+
+// countSounding sets the most notes each note shares its speaker with and the most clients playing
+// at once while it sounds, so its level doesn't change halfway through. Every client plays speakers
+// streams next to each other, 2 with -pan split, and each speaker only adds up its own notes.
+func countSounding(streams []stream, speakers int) {
+	var busy []span
+	for first := 0; first < len(streams); first += speakers {
+		last := first + speakers
+		if last > len(streams) {
+			last = len(streams)
+		}
+
+		var client []span
+		for _, s := range streams[first:last] {
+			spans := eventSpans(s.events)
+			notes := overlaps(spans)
+			for i := range s.events {
+				s.events[i].sounding = notes.most(spans[i].start, spans[i].end)
+			}
+
+			client = append(client, spans...)
+		}
+
+		busy = append(busy, overlaps(client).busy()...)
+	}
+
+	room := overlaps(busy)
+	for _, s := range streams {
+		for i := range s.events {
+			event := &s.events[i]
+			event.clients = room.most(event.rt, event.rt+event.dur)
+		}
+	}
+}
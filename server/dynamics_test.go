@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVelocityCurves(t *testing.T) {
+	tests := []struct {
+		curve    string
+		vel      uint8
+		expected float64
+	}{
+		{"linear", 127, 1},
+		{"linear", 0, 0},
+		{"sqrt", 127, 1},
+		{"sqrt", 32, math.Sqrt(32.0 / 127)},
+		{"exp", 127, 1},
+		{"exp", 0, 0},
+		{"exp", 1, math.Pow(10, -2*126.0/127)},
+		{"0,0.5,1", 127, 1},
+		{"0.2,1", 0, 0.2},
+		// halfway between the first and second point of the table
+		{"0,0.5,1", 127 / 4, 0.5 * (127 / 4) / 63.5},
+	}
+
+	for _, test := range tests {
+		c, err := parseVelocityCurve(test.curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(float64(c[test.vel])-test.expected) > 1e-6 {
+			t.Errorf("%s at %d: expected %f, got %f", test.curve, test.vel, test.expected, c[test.vel])
+		}
+	}
+
+	for _, bad := range []string{"loud", "0,2", "0,x"} {
+		if _, err := parseVelocityCurve(bad); err == nil {
+			t.Errorf("Expected an error for curve %q", bad)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	curve, _ := parseVelocityCurve("linear")
+	d := &dynamics{curve: curve, trackGains: map[int16]float32{1: 0.5}, normalize: true}
+
+	streams := []stream{
+		{events: []streamEvent{
+			{rt: 0, dur: 2 * time.Second, vel: 127, gain: 1},
+			// a chord joins the first note
+			{rt: time.Second, dur: time.Second, vel: 127, gain: 1},
+			{rt: time.Second, dur: time.Second, vel: 127, gain: 1, track: 1},
+			// back to back with the first note, while the other client plays
+			{rt: 2 * time.Second, dur: time.Second, vel: 127, gain: 1},
+			// on its own
+			{rt: 10 * time.Second, dur: time.Second, vel: 127, gain: 1},
+		}},
+		{events: []streamEvent{
+			{rt: 1500 * time.Millisecond, dur: time.Second, vel: 127, gain: 1},
+		}},
+	}
+	countSounding(streams, 1)
+
+	both := float32(1 / math.Sqrt2)
+	expected := [][]float32{
+		{both / 3, both / 3, both / 3 * 0.5, both, 1},
+		{both},
+	}
+	for i, s := range streams {
+		for j, event := range s.events {
+			if a := d.amplitude(event); math.Abs(float64(a-expected[i][j])) > 1e-6 {
+				t.Errorf("Client %d note %d: expected amplitude %f, got %f", i, j, expected[i][j], a)
+			}
+		}
+	}
+
+	// every note of the chord gets the same share, so the client never goes over full scale
+	var sum float32
+	for _, event := range streams[0].events[:3] {
+		sum += d.amplitude(event)
+	}
+	if sum > 1 {
+		t.Errorf("Expected the chord to stay under full scale, got %f", sum)
+	}
+
+	d.normalize = false
+	if a := d.amplitude(streams[0].events[1]); a != headroom {
+		t.Errorf("Expected the fixed headroom %f without normalizing, got %f", headroom, a)
+	}
+}
+
+// with -pan split the two streams of a client are one client in the room, each speaker on its own
+func TestNormalizeSplit(t *testing.T) {
+	curve, _ := parseVelocityCurve("linear")
+	d := &dynamics{curve: curve, normalize: true}
+
+	streams := []stream{
+		{events: []streamEvent{{rt: 0, dur: time.Second, vel: 127, gain: 1}}},
+		{events: []streamEvent{{rt: 0, dur: time.Second, vel: 127, gain: 1}}},
+	}
+	countSounding(streams, 2)
+
+	for i, s := range streams {
+		if a := d.amplitude(s.events[0]); a != 1 {
+			t.Errorf("Speaker %d: expected full level for a note on its own, got %f", i, a)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// effectSetting is a parameter of the effects on the clients, for one timbre or for everything
+type effectSetting struct {
+	// timbre is empty for the effects every timbre goes through
+	timbre string
+	param  string
+	value  float32
+}
+
+// parseEffects parses settings like "highpass.cutoff=120;sawtooth/lowpass.cutoff=800"
+func parseEffects(s string) ([]effectSetting, error) {
+	var settings []effectSetting
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("effect setting %q must be [timbre/]effect.param=value", part)
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 32)
+		if err != nil {
+			return nil, fmt.Errorf("effect setting %q: %w", part, err)
+		}
+
+		var e effectSetting
+		e.value = float32(value)
+		e.param = strings.TrimSpace(kv[0])
+		if i := strings.IndexByte(e.param, '/'); i >= 0 {
+			e.timbre, e.param = e.param[:i], e.param[i+1:]
+		}
+
+		if !strings.Contains(e.param, ".") {
+			return nil, fmt.Errorf("effect setting %q must be [timbre/]effect.param=value", part)
+		}
+
+		settings = append(settings, e)
+	}
+
+	return settings, nil
+}
+
+// sendEffects sets up the effects on every client, skipping timbres a client doesn't have
+func sendEffects(clients []*client, send chan<- shared.Message, settings []effectSetting) {
+	for _, c := range clients {
+		for _, e := range settings {
+			voice := uint32(shared.AllVoices)
+			if e.timbre != "" {
+				var ok bool
+				voice, ok = c.timbres[e.timbre]
+				if !ok {
+					fmt.Println("Client", c, "has no timbre", e.timbre, "for", e.param)
+					continue
+				}
+			}
+
+			send <- shared.Message{
+				Pkt:  &shared.PARAM_Packet{Voice: voice, Value: e.value, Param: e.param},
+				Addr: c.addr,
+			}
+		}
+	}
+}
@@ -17,10 +17,20 @@ type stream struct {
 }
 
 type streamEvent struct {
-	key uint8
-	vel uint8
-	dur time.Duration
-	rt  time.Duration
+	track   int16
+	channel uint8
+	key     uint8
+	vel     uint8
+	dur     time.Duration
+	rt      time.Duration
+	program uint8
+	// gain is the volume and expression of the channel, between 0 and 1
+	gain float32
+	// pan is the position of the channel between -1 (left) and 1 (right)
+	pan float32
+	// sounding is the most notes the client plays at once while this one sounds, and clients the
+	// most clients playing at once in the room, both 0 when unknown
+	sounding, clients int
 }
 
 func midiNoteToFreq(note uint8) uint32 {
@@ -49,19 +59,31 @@ type voiceEvent struct {
 	isOn bool
 	// The velocity of the note, intrepreted as amplitude
 	vel uint8
+	// The program of the channel when the note started
+	program uint8
 }
 
 // TODO pass these values as arguments through a closure
 var IVs map[int16]map[uint8]map[uint8]*voice
 var rd *reader.Reader
 
+// programs is the current General MIDI program of each channel
+var programs [16]uint8
+
+// ccs holds the controller changes of the song
+var ccs *controllers
+
 func makeIV(filename string) ([]*voice, error) {
 	IVs = make(map[int16]map[uint8]map[uint8]*voice)
+	programs = [16]uint8{}
+	ccs = newControllers()
 
 	// to disable logging, pass mid.NoLogger() as option
 	rd = reader.New(reader.NoLogger(),
 		reader.NoteOn(noteOn),
 		reader.NoteOff(noteOff),
+		reader.ProgramChange(programChange),
+		reader.ControlChange(controlChange),
 	)
 
 	err := reader.ReadSMFFile(rd, filename)
@@ -69,6 +91,8 @@ func makeIV(filename string) ([]*voice, error) {
 		return nil, err
 	}
 
+	ccs.sort()
+
 	// the extract used voices
 	voices := make([]*voice, 0)
 	for _, channels := range IVs {
@@ -79,6 +103,12 @@ func makeIV(filename string) ([]*voice, error) {
 		}
 	}
 
+	for _, voice := range voices {
+		if n := len(voice.events); n > 0 && voice.events[n-1].rt > ccs.end {
+			ccs.end = voice.events[n-1].rt
+		}
+	}
+
 	// sort by total on time, using this we can fairly merge the voices
 	sort.Slice(voices, func(i, j int) bool {
 		return voices[i].totalOnTime > voices[j].totalOnTime
@@ -87,84 +117,114 @@ func makeIV(filename string) ([]*voice, error) {
 	return voices, nil
 }
 
+// voiceEvents turns the on and off events of a voice into notes
+func voiceEvents(voice *voice) []streamEvent {
+	events := make([]streamEvent, 0)
+
+	for i := 0; i < len(voice.events)-1; i++ {
+		event := voice.events[i]
+		next := voice.events[i+1]
+
+		// how long the note was on
+		end := next.rt
+
+		// the sustain pedal holds the note after it is released, until the key is played again
+		if !next.isOn {
+			end = ccs.sustainEnd(voice.channel, next.rt)
+			if i+2 < len(voice.events) && voice.events[i+2].rt < end {
+				end = voice.events[i+2].rt
+			}
+		}
+
+		if event.isOn {
+			events = append(events, streamEvent{
+				track:   voice.track,
+				channel: voice.channel,
+				key:     voice.key,
+				vel:     event.vel,
+				dur:     end - event.rt,
+				rt:      event.rt,
+				program: event.program,
+				gain:    ccs.gain(voice.channel, event.rt),
+				pan:     ccs.pan(voice.channel, event.rt),
+			})
+		}
+	}
+
+	return events
+}
+
 // Fairly merges all the voice events into n voices
-// Also returns the duration of the song
-func merge(voices []*voice, n int) ([]stream, time.Duration) {
+func merge(voices []*voice, n int) []stream {
 	fmt.Println("Merging", len(voices), "voices into", n, "streams")
-	if n == 0 {
-		panic("n must be > 0")
-	}
 
 	// Convert the voice structs into stream structs
 	streams := make([]stream, len(voices))
 
 	// turn the voices into streams
 	for i, voice := range voices {
-		stream := stream{
-			events:      make([]streamEvent, 0),
+		streams[i] = stream{
+			events:      voiceEvents(voice),
 			totalOnTime: voice.totalOnTime,
 		}
+	}
 
-		for i := 0; i < len(voice.events)-1; i++ {
-			event := voice.events[i]
-			next := voice.events[i+1]
-
-			// how long the note was on
-			d := next.rt - event.rt
-
-			if event.isOn {
-				stream.events = append(stream.events, streamEvent{
-					key: voice.key,
-					vel: event.vel,
-					dur: d,
-					rt:  event.rt,
-				})
-			}
-		}
+	return bin(streams, n)
+}
 
-		streams[i] = stream
+// bin packs the parts into n streams, each part going to the stream with the least total on time so far.
+// Parts are packed in order, so sorting them from longest to shortest gives the fairest result
+func bin(parts []stream, n int) []stream {
+	if n == 0 {
+		panic("n must be > 0")
 	}
 
 	// group the streams into n groups
 	groups := make([]stream, n)
-	totals := make([]time.Duration, n)
 	for i := 0; i < n; i++ {
 		groups[i].events = make([]streamEvent, 0)
 	}
 
-	for _, stream := range streams {
+	for _, part := range parts {
 		// find the smallest group
 		min := 0
 		for i := 1; i < n; i++ {
-			if totals[i] < totals[min] {
+			if groups[i].totalOnTime < groups[min].totalOnTime {
 				min = i
 			}
 		}
 
 		// add all events to the group
-		groups[min].events = append(groups[min].events, stream.events...)
-		totals[min] += stream.totalOnTime
+		groups[min].events = append(groups[min].events, part.events...)
+		groups[min].totalOnTime += part.totalOnTime
 	}
 
 	// Sort groups events by real time
+	totals := make([]time.Duration, n)
 	for i := 0; i < n; i++ {
 		sort.Slice(groups[i].events, func(j, k int) bool {
 			return groups[i].events[j].rt < groups[i].events[k].rt
 		})
+		totals[i] = groups[i].totalOnTime
 	}
 
-	// Find the duration of the song
+	fmt.Println("Note on times for each stream:", totals)
+
+	return groups
+}
+
+// songDuration finds when the last note of the song ends
+func songDuration(streams []stream) time.Duration {
 	var duration time.Duration
 	for _, stream := range streams {
-		// check the last event
-		if stream.events[len(stream.events)-1].rt > duration {
-			duration = stream.events[len(stream.events)-1].rt
+		for _, event := range stream.events {
+			if event.rt+event.dur > duration {
+				duration = event.rt + event.dur
+			}
 		}
 	}
 
-	fmt.Println("Note on times for each stream:", totals)
-
-	return groups, duration
+	return duration
 }
 
 // Makes sure that the key is in the map
@@ -187,10 +247,11 @@ func noteOn(p *reader.Position, channel, key, vel uint8) {
 
 	rt := *reader.TimeAt(rd, p.AbsoluteTicks)
 	IVs[p.Track][channel][key].events = append(IVs[p.Track][channel][key].events, voiceEvent{
-		ticks: p.AbsoluteTicks,
-		rt:    rt,
-		isOn:  true,
-		vel:   vel,
+		ticks:   p.AbsoluteTicks,
+		rt:      rt,
+		isOn:    true,
+		vel:     vel,
+		program: programs[channel%16],
 	})
 
 	IVs[p.Track][channel][key].lastOn = rt
@@ -209,3 +270,11 @@ func noteOff(p *reader.Position, channel, key, vel uint8) {
 
 	IVs[p.Track][channel][key].totalOnTime += rt - IVs[p.Track][channel][key].lastOn
 }
+
+func programChange(p *reader.Position, channel, program uint8) {
+	programs[channel%16] = program
+}
+
+func controlChange(p *reader.Position, channel, controller, value uint8) {
+	ccs.add(channel, controller, value, *reader.TimeAt(rd, p.AbsoluteTicks))
+}
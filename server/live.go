@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+	"gitlab.com/gomidi/midi/reader"
+)
+
+// Notes played live have no known duration, they are held until the matching note off
+const liveHold = time.Minute
+
+// Drums ignore their note off, a live hit rings for this long at most
+const liveDrumHold = time.Second
+
+// live reads raw MIDI messages from src (stdin, a FIFO, a raw midi device, or a recording of one)
+// and plays them on the clients as they arrive. Returns nil when src is exhausted.
+func live(src io.Reader, clients []*client, send chan<- shared.Message, policy stealPolicy, v *voicing, drums drumRouting) error {
+	if len(clients) == 0 {
+		return errors.New("live: no clients to play on")
+	}
+
+	pitchedN, drumsN, err := drums.clients(len(clients))
+	if err != nil {
+		return fmt.Errorf("live: %w", err)
+	}
+
+	// every client gets as many voices as it can play at once, owners says whose each voice is.
+	// Drums don't need a voice of their own, they are dealt out in turn to the last drumsN clients.
+	owners := voiceOwners(clients[:pitchedN])
+	alloc := newAllocator(len(owners), policy)
+	nextDrum := 0
+	start := time.Now()
+
+	// Live notes can't be sent early, so instead every client is held back by however much
+	// less latency it has than the slowest client. Each client gets its own queue to keep
+	// its PLAY and STOP packets in order.
+	type delayed struct {
+		at  time.Time
+		msg shared.Message
+	}
+
+	l := lead(clients)
+	queues := make([]chan delayed, len(clients))
+	for i := range clients {
+		queues[i] = make(chan delayed, 50)
+
+		go func(q <-chan delayed) {
+			for d := range q {
+				time.Sleep(time.Until(d.at))
+				send <- d.msg
+			}
+		}(queues[i])
+	}
+
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+	}()
+
+	deliver := func(i int, pkt shared.Packet) {
+		at := time.Now().Add(l - clients[i].compensation())
+		queues[i] <- delayed{
+			at:  at,
+			msg: shared.Message{Pkt: stamp(pkt, at), Addr: clients[i].addr},
+		}
+	}
+
+	// the current General MIDI program of each channel
+	var programs [16]uint8
+	ccs := newLiveControllers()
+
+	// keys let go of while the sustain pedal is down, by channel. They keep sounding until the pedal is let go.
+	sustained := make(map[uint8][]uint8)
+
+	release := func(channel, key uint8) {
+		i := alloc.noteOff(channel, key, time.Since(start))
+		if i == -1 {
+			return
+		}
+
+		deliver(owners[i], stopKey(key))
+	}
+
+	noteOn := func(_ *reader.Position, channel, key, vel uint8) {
+		now := time.Since(start)
+		if channel == percussionChannel {
+			if drumsN == 0 {
+				return
+			}
+
+			i := len(clients) - drumsN + nextDrum%drumsN
+			nextDrum++
+			deliver(i, v.drum(clients[i], streamEvent{
+				channel: channel,
+				key:     key,
+				vel:     vel,
+				dur:     liveDrumHold,
+				gain:    ccs.gain(channel, now),
+				pan:     ccs.pan(channel, now),
+			}))
+			return
+		}
+
+		// playing a sustained key again ends the held note first
+		for j, k := range sustained[channel] {
+			if k == key {
+				sustained[channel] = append(sustained[channel][:j], sustained[channel][j+1:]...)
+				release(channel, key)
+				break
+			}
+		}
+
+		// live MIDI has no tracks, the channel ranks the notes for the priority policy instead
+		i, prev, stolen := alloc.noteOn(note{
+			track:   int16(channel),
+			channel: channel,
+			key:     key,
+			vel:     vel,
+			start:   now,
+		})
+		if i == -1 {
+			return
+		}
+
+		// silence the note we are stealing the voice from
+		if stolen {
+			deliver(owners[i], stopKey(prev.key))
+		}
+
+		// live notes are counted as they start, the notes after them don't change their level
+		c := owners[i]
+		sounding, playing := 0, make(map[int]bool)
+		for j, s := range alloc.slots {
+			if s.busy {
+				playing[owners[j]] = true
+				if owners[j] == c {
+					sounding++
+				}
+			}
+		}
+
+		deliver(c, v.play(clients[c], streamEvent{
+			channel:  channel,
+			key:      key,
+			vel:      vel,
+			dur:      liveHold,
+			program:  programs[channel%16],
+			gain:     ccs.gain(channel, now),
+			pan:      ccs.pan(channel, now),
+			sounding: sounding,
+			clients:  len(playing),
+		}))
+	}
+
+	noteOff := func(_ *reader.Position, channel, key, _ uint8) {
+		if channel == percussionChannel {
+			return
+		}
+
+		if ccs.at(channel, ccSustain, time.Since(start)) >= 64 {
+			sustained[channel] = append(sustained[channel], key)
+			return
+		}
+
+		release(channel, key)
+	}
+
+	programChange := func(_ *reader.Position, channel, program uint8) {
+		programs[channel%16] = program
+	}
+
+	controlChange := func(_ *reader.Position, channel, controller, value uint8) {
+		ccs.add(channel, controller, value, time.Since(start))
+
+		// letting go of the pedal releases every key it was holding
+		if controller == ccSustain && value < 64 {
+			for _, key := range sustained[channel] {
+				release(channel, key)
+			}
+			delete(sustained, channel)
+		}
+	}
+
+	rd := reader.New(reader.NoLogger(),
+		reader.NoteOn(noteOn),
+		reader.NoteOff(noteOff),
+		reader.ProgramChange(programChange),
+		reader.ControlChange(controlChange),
+	)
+
+	err = reader.ReadAllFrom(rd, src)
+	fmt.Println("Stole", alloc.stolen, "notes and dropped", alloc.dropped)
+
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
+
+// voiceOwners lists the client of every voice. The clients take turns so the notes spread over
+// all of them before any client plays two at once.
+func voiceOwners(clients []*client) []int {
+	var owners []int
+	for round := 0; round < midiKeys; round++ {
+		for i, c := range clients {
+			if c.polyphony() > round {
+				owners = append(owners, i)
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+func TestVoiceOwners(t *testing.T) {
+	clients := []*client{{voices: 2}, {voices: 1}, {voices: 3}}
+
+	// every client gets a voice before any gets a second
+	expected := []int{0, 1, 2, 0, 2, 2}
+	got := voiceOwners(clients)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+
+	// a client without a limit can hold every key
+	if n := len(voiceOwners([]*client{{}})); n != midiKeys {
+		t.Errorf("Expected %d voices for a client without a limit, got %d", midiKeys, n)
+	}
+}
+
+// liveClient is a client holding a single note, latency behind the speaker
+func liveClient(port int, latency time.Duration) *client {
+	return &client{
+		addr:       &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port},
+		latency:    latency,
+		hasLatency: true,
+		voices:     1,
+	}
+}
+
+// packetKey is the key a PLAY or STOP is for, with a minus for STOP
+func packetKey(pkt shared.Packet) int {
+	switch p := pkt.(type) {
+	case *shared.PLAY_Packet:
+		return int(p.Key)
+	case *shared.STOP_Packet:
+		return -int(p.Key)
+	}
+	return 0
+}
+
+func TestLive(t *testing.T) {
+	fast, slow := liveClient(1, 0), liveClient(2, 40*time.Millisecond)
+	clients := []*client{fast, slow}
+
+	// two keys fill both voices, a softer third is dropped and a louder fourth steals the voice of
+	// the softest. Written with running status, the way a keyboard sends them.
+	src := bytes.NewReader([]byte{
+		0x90, 60, 100, 62, 90, 64, 80, 67, 120,
+		0x80, 60, 0, 64, 0, 67, 0, 62, 0,
+	})
+
+	send := make(chan shared.Message, 50)
+	v := &voicing{dynamics: &dynamics{curve: &velocityCurve{}}}
+	if err := live(src, clients, send, stealQuietest, v, drumRouting{drop: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the packets of the fast client are held back until the slow client catches up
+	expected := map[*client][]int{
+		fast: {60, -60},
+		slow: {62, -62, 67, -67},
+	}
+
+	got := make(map[*client][]int)
+	arrived := make(map[*client]time.Time)
+	dues := make(map[*client]uint32)
+	timeout := time.After(time.Second)
+	for n := 0; n < 6; n++ {
+		select {
+		case msg := <-send:
+			c := byAddr(clients, msg.Addr)
+			if _, ok := arrived[c]; !ok {
+				arrived[c] = time.Now()
+				dues[c] = msg.Pkt.(*shared.PLAY_Packet).Due
+			}
+			got[c] = append(got[c], packetKey(msg.Pkt))
+		case <-timeout:
+			t.Fatalf("Expected 6 packets, got %v", got)
+		}
+	}
+
+	select {
+	case msg := <-send:
+		t.Errorf("Expected nothing for the dropped key, got %v", msg.Pkt)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for c, keys := range expected {
+		if len(got[c]) != len(keys) {
+			t.Fatalf("Expected %v for %s, got %v", keys, c.addr, got[c])
+		}
+		for i := range keys {
+			if got[c][i] != keys[i] {
+				t.Fatalf("Expected %v for %s, got %v", keys, c.addr, got[c])
+			}
+		}
+	}
+
+	if d := arrived[fast].Sub(arrived[slow]); d < 30*time.Millisecond {
+		t.Errorf("Expected the fast client to be sent its notes 40ms later, got %v", d)
+	}
+	if d := time.Duration(int32(dues[fast]-dues[slow])) * time.Millisecond; d < 35*time.Millisecond || d > 45*time.Millisecond {
+		t.Errorf("Expected the fast client's notes to be due 40ms later, got %v", d)
+	}
+}
+
+func TestLiveNormalize(t *testing.T) {
+	c := liveClient(1, 0)
+	c.voices = 2
+
+	// a second key shares the client with the first
+	src := bytes.NewReader([]byte{0x90, 60, 127, 64, 127})
+
+	send := make(chan shared.Message, 50)
+	curve, _ := parseVelocityCurve("linear")
+	v := &voicing{dynamics: &dynamics{curve: curve, normalize: true}}
+	if err := live(src, []*client{c}, send, stealOldest, v, drumRouting{drop: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []float32{1, 0.5} {
+		select {
+		case msg := <-send:
+			if a := msg.Pkt.(*shared.PLAY_Packet).Amplitude; a != expected {
+				t.Errorf("Expected amplitude %v, got %v", expected, a)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected a PLAY for both keys")
+		}
+	}
+}
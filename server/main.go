@@ -1,26 +1,147 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"math"
+	"io"
 	"math/rand"
 	"net"
 	"os"
 	"os/signal"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/Alextopher/itl-chorus/shared"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 func main() {
 	// initilize rng
 	rand.Seed(time.Now().UnixNano())
 
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: midi-reader <midifile>")
+	liveSrc := flag.String("live", "", "play a raw MIDI byte stream from this file or FIFO as it arrives (\"-\" for stdin)")
+	partition := flag.String("partition", "fair", "how to split the song between clients: "+partitionerNames)
+	steal := flag.String("steal", "oldest", "which note loses its client when all are busy: oldest, quietest or priority (highest track number, or channel when live)")
+	roomFile := flag.String("room", "", "JSON file with the client positions, streams are handed out to the clients in room order")
+	move := flag.Duration("move", 0, "pass every stream on to the next client in room order this often, so the parts move around the room (0 keeps them in place, not used with -live)")
+	calibrateMode := flag.Bool("calibrate", false, "click on every client at once until interrupted, to tune the offsets in the room file by ear")
+	calibrateTimbre := flag.String("calibrate-timbre", "square", "timbre of the -calibrate clicks, pink gives noise bursts which are easier to line up by ear")
+	timbre := flag.String("timbre", "sawtooth", "name of the timbre the clients play, see the client's -timbres")
+	envelope := flag.String("envelope", "", "envelope for every note as attack,decay,sustain,release (e.g. 10ms,100ms,0.7,200ms), by default the clients choose")
+	drumsFlag := flag.String("drums", "mix", "who plays the percussion channel: mix (any client), drop, or a number of clients that only play drums")
+	curveFlag := flag.String("velocity-curve", "sqrt", "how velocity turns into loudness: linear, sqrt, exp or a table of amplitudes spread over the velocities (e.g. 0,0.4,0.7,1)")
+	trackGainFlag := flag.String("track-gain", "", "gain of some tracks as track=gain separated by ',', e.g. 2=0.5 to bring down an accompaniment")
+	normalize := flag.Bool("normalize", false, "scale notes by how many play at once on their client and in the room, so chords don't clip and solos stay at full level, instead of playing every note at half level")
+	panFlag := flag.String("pan", "midi", "where clients with stereo speakers place notes: midi (the song's pan controller), room (the client's x in -room), none, or split to give every client two streams, one for each speaker")
+	effectsFlag := flag.String("effects", "", "effect parameters to set on the clients as [timbre/]effect.param=value separated by ';', e.g. highpass.cutoff=120 for small speakers")
+	analyzeN := flag.Int("analyze", 0, "print the polyphony report for this many clients and exit without playing")
+	flag.Parse()
+
+	policy, err := parseStealPolicy(*steal)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	partitioner, err := parsePartitioner(*partition, policy)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *move < 0 {
+		fmt.Println("-move must not be negative")
+		os.Exit(1)
+	}
+	if *move > 0 {
+		partitioner = moving{partitioner: partitioner, every: *move}
+	}
+
+	drums, err := parseDrumRouting(*drumsFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	effects, err := parseEffects(*effectsFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	pan, err := parsePanSource(*panFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	curve, err := parseVelocityCurve(*curveFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	trackGains, err := parseTrackGains(*trackGainFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	v := &voicing{
+		timbre:   *timbre,
+		pan:      pan,
+		dynamics: &dynamics{curve: curve, trackGains: trackGains, normalize: *normalize},
+	}
+	if *envelope != "" {
+		e, err := shared.ParseEnvelope(*envelope)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		v.envelope = &e
+	}
+
+	needsFile := *liveSrc == "" && !*calibrateMode
+	if (needsFile && flag.NArg() < 1) || (!needsFile && flag.NArg() != 0) {
+		fmt.Println("Usage: midi-reader <midifile>...")
+		fmt.Println("       midi-reader -live <stream>")
+		fmt.Println("       midi-reader -calibrate")
+		os.Exit(1)
+	}
+
+	if *analyzeN > 0 {
+		for _, filename := range flag.Args() {
+			fmt.Println(filename)
+
+			voices, err := makeIV(filename)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			streams, err := drums.partition(partitioner, voices, *analyzeN)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			report(voices, streams, drums)
+		}
+		return
+	}
+
+	var r *room
+	if *roomFile != "" {
+		r, err = loadRoom(*roomFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else if pan == panRoom {
+		fmt.Println("-pan room needs the client positions from -room")
+		os.Exit(1)
+	}
+
+	if pan == panSplit && *liveSrc != "" {
+		fmt.Println("-pan split can't be used with -live")
 		os.Exit(1)
 	}
 
@@ -43,37 +164,76 @@ func main() {
 	// Create a ping packet
 	ping := shared.RandomPing()
 
-	clients := make([]*net.UDPAddr, 0)
+	clients := make([]*client, 0)
 
 	// Listen for incoming messages for 5 second
 	timer := time.NewTimer(time.Second * 5)
+
+	// the clients answer a ping with their latency, ask again until they do
+	retry := time.NewTicker(time.Second)
+	defer retry.Stop()
 Loop:
 	for {
 		select {
+		case <-retry.C:
+			for _, c := range clients {
+				if !c.hasLatency {
+					send <- shared.Message{Pkt: &ping, Addr: c.addr}
+				}
+			}
 		case msg := <-recv:
 			switch msg.Pkt.(type) {
 			case *shared.CAPS_Packet:
+				caps := msg.Pkt.(*shared.CAPS_Packet)
+
 				// We can only support "gogo" clients
-				if msg.Pkt.(*shared.CAPS_Packet).Name != "gogo" {
-					fmt.Println("Unsupported client:", msg.Pkt.(*shared.CAPS_Packet).Name)
+				if caps.Name != "gogo" {
+					fmt.Println("Unsupported client:", caps.Name)
 					break
 				}
 
-				// Check if we already have this client
-				for _, client := range clients {
-					if client.String() == msg.Addr.String() {
-						continue Loop
+				// Check if we already have this client, a restarted client keeps its identity but may change port
+				for _, c := range clients {
+					if c.identity != caps.Identity {
+						continue
+					}
+
+					// a restarted client may play a different number of notes
+					c.voices = int(caps.NumVoices)
+
+					if c.addr.String() != msg.Addr.String() {
+						c.addr = msg.Addr
+						fmt.Println("Client reconnected:", c)
+
+						send <- shared.Message{
+							Pkt:  &ping,
+							Addr: msg.Addr,
+						}
 					}
+
+					continue Loop
 				}
 
-				clients = append(clients, msg.Addr)
-				fmt.Println("Client connected:", msg.Addr)
+				c := newClient(msg.Addr, caps.Identity, r)
+				c.voices = int(caps.NumVoices)
+				clients = append(clients, c)
+				fmt.Println("Client connected:", c)
 
 				// Send a PING packet
 				send <- shared.Message{
-					Pkt:  ping,
+					Pkt:  &ping,
 					Addr: msg.Addr,
 				}
+			case *shared.TIMBRE_Packet:
+				if c := byAddr(clients, msg.Addr); c != nil {
+					timbre := msg.Pkt.(*shared.TIMBRE_Packet)
+					c.timbres[timbre.Name] = timbre.Voice
+				}
+			case *shared.LATENCY_Packet:
+				if c := byAddr(clients, msg.Addr); c != nil && !c.hasLatency {
+					c.latency, c.hasLatency = msg.Pkt.(*shared.LATENCY_Packet).Latency, true
+					fmt.Println("Client", c, "has output latency", c.latency)
+				}
 			}
 		case <-timer.C:
 			break Loop
@@ -81,6 +241,29 @@ Loop:
 	}
 
 	fmt.Println("Found", len(clients), "clients")
+	for _, c := range clients {
+		if !c.hasLatency {
+			fmt.Println("Client", c, "hasn't reported its output latency yet, it plays without compensation until it does")
+		}
+	}
+
+	sendEffects(clients, send, effects)
+
+	if *calibrateMode {
+		checkTimbre(clients, *calibrateTimbre)
+	} else {
+		checkTimbre(clients, *timbre)
+	}
+
+	if r != nil {
+		r.arrange(clients)
+		r.place(clients)
+		for i, c := range clients {
+			fmt.Println("Stream", i, "->", c)
+		}
+	}
+
+	go watch(recv, clients)
 
 	// Handle sys interrupt
 	go func() {
@@ -88,106 +271,100 @@ Loop:
 		signal.Notify(sig, os.Interrupt)
 		<-sig
 
-		pkt := &shared.QUIT_Packet{}
-		for _, client := range clients {
-			send <- shared.Message{
-				Pkt:  pkt,
-				Addr: client,
-			}
-		}
-
-		// Wait for 1 second to make sure all packets are sent
-		time.Sleep(time.Second)
+		leaveScreen()
+		quit(send, clients)
 		os.Exit(1)
 	}()
 
-	start := time.Now()
-	voices, err := makeIV(os.Args[1])
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	streams, duration := merge(voices, len(clients))
-	fmt.Println("Duration:", duration)
+	// the pings measure the round trip time and get the latency of clients that haven't reported it
+	stopPings := make(chan struct{})
+	go pinger(clients, send, stopPings)
 
-	if len(streams) != len(clients) {
-		fmt.Println("Found", len(streams), "streams, but we have", len(clients), "clients. Ignoring the extra clients.")
-		clients = clients[:len(voices)]
+	if *calibrateMode {
+		calibrate(clients, send, *calibrateTimbre)
 	}
 
-	// begin streaming the voices
-	start = time.Now()
-
-	wg := &sync.WaitGroup{}
-	for i, client := range clients {
-		stream := streams[i]
-		wg.Add(1)
-
-		go func(client *net.UDPAddr, i uint32) {
-			defer wg.Done()
+	if *liveSrc != "" {
+		var src io.Reader = os.Stdin
+		if *liveSrc != "-" {
+			f, err := os.Open(*liveSrc)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			src = f
+		}
 
-			for _, event := range stream.events {
-				// Sleep until the event is due
-				time.Sleep(time.Until(start.Add(event.rt)))
+		fmt.Println("Playing live from", *liveSrc)
+		if err := live(src, clients, send, policy, v, drums); err != nil {
+			fmt.Println(err)
+		}
 
-				pkt := shared.PLAY_Packet{
-					Duration:  event.dur,
-					Frequency: midiNoteToFreq(event.key),
-					Amplitude: float32(math.Sqrt(float64(event.vel)/float64(128))) / 2, // TODO Amplitude should be dependent on the number of clients
-					Voice:     1,
-				}
+		close(stopPings)
+		finish(send, clients)
+		return
+	}
 
-				send <- shared.Message{
-					Pkt:  &pkt,
-					Addr: client,
-				}
-			}
-		}(client, uint32(i))
+	// with -pan split every client plays a stream on each of its speakers
+	stereo, speakers := clients, 1
+	if pan == panSplit {
+		stereo, speakers = stereoPairs(clients), 2
 	}
 
-	// progress bar
-	go func() {
-		// Calculate the terminal width
-		width, _, err := terminal.GetSize(int(os.Stdin.Fd()))
+	for i, filename := range flag.Args() {
+		s, err := loadSong(filename, partitioner, drums, len(stereo), speakers)
 		if err != nil {
 			fmt.Println(err)
-			return
+			quit(send, clients)
+			os.Exit(1)
 		}
 
-		// trim the width so there is room to print "Progress: " and the percentage
-		width -= 20
+		if pan == panSplit {
+			splitStereo(s.streams)
+		}
 
-		start := time.Now()
-		for {
-			// Calculate the progress
-			progress := float64(time.Since(start)) / float64(duration)
-			if progress > 1 {
-				progress = 1
-			}
+		players := stereo
+		if len(s.streams) != len(players) {
+			fmt.Println("Found", len(s.streams), "streams, but we have", len(players), "clients. Ignoring the extra clients.")
+			players = players[:len(s.streams)]
+		}
 
-			// Print the progress bar
-			fmt.Printf("\rProgress: [%s%s] %.2f%%",
-				strings.Repeat("=", int(progress*float64(width))),
-				strings.Repeat(" ", int((1-progress)*float64(width))),
-				progress*100,
-			)
+		// begin streaming the voices, leaving room to send notes early to the clients with the most latency
+		tr := newTransport(time.Now().Add(lead(players)))
 
-			time.Sleep(time.Millisecond * 100)
+		d := &dashboard{song: s, index: i, count: flag.NArg(), clients: players, tr: tr}
+		stop := make(chan struct{})
+		shown := make(chan struct{})
+		go func() {
+			d.run(stop)
+			close(shown)
+		}()
+
+		playSong(s, players, send, v, tr)
+
+		close(stop)
+		<-shown
+
+		if tr.now().quit {
+			break
 		}
-	}()
+	}
 
-	wg.Wait()
+	close(stopPings)
+	finish(send, clients)
+}
 
+// quit tells every client the song is over
+func quit(send chan<- shared.Message, clients []*client) {
 	pkt := &shared.QUIT_Packet{}
-	for _, client := range clients {
+	for _, c := range clients {
 		send <- shared.Message{
 			Pkt:  pkt,
-			Addr: client,
+			Addr: c.addr,
 		}
 	}
 
 	// Wait for 1 second to make sure all packets are sent
 	time.Sleep(time.Second)
-	fmt.Print("\n")
 }
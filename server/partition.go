@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// partitioner splits the voices of a song into n streams, one per client
+type partitioner interface {
+	partition(voices []*voice, n int) []stream
+}
+
+// partitionerNames lists the strategies accepted by parsePartitioner
+const partitionerNames = "fair, track, pitch, roundrobin, polyphony or dynamic"
+
+func parsePartitioner(name string, policy stealPolicy) (partitioner, error) {
+	switch name {
+	case "fair":
+		return fairTime{}, nil
+	case "track":
+		return byTrack{}, nil
+	case "pitch":
+		return byPitch{}, nil
+	case "roundrobin":
+		return roundRobin{}, nil
+	case "polyphony":
+		return minPolyphony{}, nil
+	case "dynamic":
+		return dynamic{policy: policy}, nil
+	default:
+		return nil, fmt.Errorf("unknown partitioner %q (expected %s)", name, partitionerNames)
+	}
+}
+
+// fairTime gives every stream roughly the same total on time by merging whole voices
+type fairTime struct{}
+
+func (fairTime) partition(voices []*voice, n int) []stream {
+	return merge(voices, n)
+}
+
+// byTrack keeps every (track, channel) pair together as one part.
+// When there are more parts than clients they are packed fairly.
+type byTrack struct{}
+
+func (byTrack) partition(voices []*voice, n int) []stream {
+	type part struct {
+		track   int16
+		channel uint8
+	}
+
+	index := make(map[part]int)
+	parts := make([]stream, 0)
+	for _, voice := range voices {
+		k := part{voice.track, voice.channel}
+
+		i, ok := index[k]
+		if !ok {
+			i = len(parts)
+			index[k] = i
+			parts = append(parts, stream{})
+		}
+
+		parts[i].events = append(parts[i].events, voiceEvents(voice)...)
+		parts[i].totalOnTime += voice.totalOnTime
+	}
+
+	fmt.Println("Splitting", len(parts), "tracks and channels into", n, "streams")
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].totalOnTime > parts[j].totalOnTime
+	})
+
+	return bin(parts, n)
+}
+
+// byPitch splits the keys into n contiguous ranges with about the same on time,
+// stream 0 gets the bass and stream n-1 the soprano
+type byPitch struct{}
+
+func (byPitch) partition(voices []*voice, n int) []stream {
+	if n == 0 {
+		panic("n must be > 0")
+	}
+
+	sorted := make([]*voice, len(voices))
+	copy(sorted, voices)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+
+	var total time.Duration
+	for _, voice := range sorted {
+		total += voice.totalOnTime
+	}
+
+	streams := make([]stream, n)
+	var sofar time.Duration
+	for _, voice := range sorted {
+		// the range this voice falls in, based on the on time of every lower voice
+		i := int(int64(n) * int64(sofar) / int64(total+1))
+
+		streams[i].events = append(streams[i].events, voiceEvents(voice)...)
+		streams[i].totalOnTime += voice.totalOnTime
+		sofar += voice.totalOnTime
+	}
+
+	for i := range streams {
+		sortEvents(streams[i].events)
+	}
+
+	return streams
+}
+
+// roundRobin deals the notes out one at a time in the order they start
+type roundRobin struct{}
+
+func (roundRobin) partition(voices []*voice, n int) []stream {
+	if n == 0 {
+		panic("n must be > 0")
+	}
+
+	streams := make([]stream, n)
+	for j, event := range flatten(voices) {
+		i := j % n
+		streams[i].events = append(streams[i].events, event)
+		streams[i].totalOnTime += event.dur
+	}
+
+	return streams
+}
+
+// minPolyphony gives every note to a stream that is silent when it starts, or else to the stream
+// whose note ends the soonest. Every note is kept, overlaps are only as long as they have to be.
+type minPolyphony struct{}
+
+func (minPolyphony) partition(voices []*voice, n int) []stream {
+	if n == 0 {
+		panic("n must be > 0")
+	}
+
+	streams := make([]stream, n)
+	ends := make([]time.Duration, n)
+	for _, event := range flatten(voices) {
+		i := 0
+		for j := 1; j < n; j++ {
+			// among the silent streams prefer the one with the least on time
+			if ends[j] <= event.rt && ends[i] <= event.rt {
+				if streams[j].totalOnTime < streams[i].totalOnTime {
+					i = j
+				}
+			} else if ends[j] < ends[i] {
+				i = j
+			}
+		}
+
+		streams[i].events = append(streams[i].events, event)
+		streams[i].totalOnTime += event.dur
+		if event.rt+event.dur > ends[i] {
+			ends[i] = event.rt + event.dur
+		}
+	}
+
+	return streams
+}
+
+// dynamic uses the allocator so every stream plays a single note at a time
+type dynamic struct {
+	policy stealPolicy
+}
+
+func (d dynamic) partition(voices []*voice, n int) []stream {
+	return allocate(voices, n, d.policy)
+}
+
+// flatten collects the notes of every voice sorted by start time
+func flatten(voices []*voice) []streamEvent {
+	events := make([]streamEvent, 0)
+	for _, voice := range voices {
+		events = append(events, voiceEvents(voice)...)
+	}
+
+	sortEvents(events)
+	return events
+}
+
+func sortEvents(events []streamEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].rt < events[j].rt
+	})
+}
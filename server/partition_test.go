@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// partitionVoices are five single note voices on three tracks, never more than three sounding at once
+func partitionVoices() []*voice {
+	note := func(track int16, key uint8, start, dur time.Duration) *voice {
+		return &voice{
+			track:   track,
+			channel: uint8(track),
+			key:     key,
+			events: []voiceEvent{
+				{rt: start, isOn: true, vel: 100},
+				{rt: start + dur, isOn: false},
+			},
+			totalOnTime: dur,
+		}
+	}
+
+	return []*voice{
+		note(0, 40, 0, 4*time.Second),
+		note(0, 52, time.Second, time.Second),
+		note(1, 64, 0, time.Second),
+		note(1, 76, 2*time.Second, time.Second),
+		note(2, 88, 500*time.Millisecond, time.Second),
+	}
+}
+
+func TestPartitioners(t *testing.T) {
+	for _, name := range []string{"fair", "track", "pitch", "roundrobin", "polyphony", "dynamic"} {
+		p, err := parsePartitioner(name, stealOldest)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		streams := p.partition(partitionVoices(), 3)
+		if len(streams) != 3 {
+			t.Errorf("%s: expected 3 streams, got %d", name, len(streams))
+			continue
+		}
+
+		notes := 0
+		for i, s := range streams {
+			notes += len(s.events)
+			for j := 1; j < len(s.events); j++ {
+				if s.events[j].rt < s.events[j-1].rt {
+					t.Errorf("%s: expected the notes of stream %d in order", name, i)
+				}
+			}
+		}
+		if notes != 5 {
+			t.Errorf("%s: expected every one of the 5 notes to be played, got %d", name, notes)
+		}
+	}
+
+	if _, err := parsePartitioner("loudest", stealOldest); err == nil {
+		t.Error("Expected an error for an unknown partitioner")
+	}
+}
+
+// streamKeys lists the keys of the notes of every stream
+func streamKeys(streams []stream) [][]uint8 {
+	ks := make([][]uint8, len(streams))
+	for i, s := range streams {
+		for _, event := range s.events {
+			ks[i] = append(ks[i], event.key)
+		}
+	}
+	return ks
+}
+
+func TestByPitch(t *testing.T) {
+	streams := byPitch{}.partition(partitionVoices(), 3)
+
+	// every stream is higher than the one before
+	for i := 1; i < len(streams); i++ {
+		for _, low := range streams[i-1].events {
+			for _, high := range streams[i].events {
+				if low.key > high.key {
+					t.Errorf("Expected stream %d to be below stream %d, got %v", i-1, i, streamKeys(streams))
+				}
+			}
+		}
+	}
+
+	if bass := streams[0].events; len(bass) == 0 || bass[0].key != 40 {
+		t.Errorf("Expected the bass to start the first stream, got %v", streamKeys(streams))
+	}
+}
+
+func TestByTrack(t *testing.T) {
+	for i, s := range (byTrack{}).partition(partitionVoices(), 3) {
+		for _, event := range s.events {
+			if event.track != s.events[0].track {
+				t.Errorf("Expected stream %d to play a single track, got tracks %d and %d", i, s.events[0].track, event.track)
+			}
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	// dealt in the order the notes start, the voices keep their order when they start together
+	expected := [][]uint8{{40, 52}, {64, 76}, {88}}
+
+	got := streamKeys(roundRobin{}.partition(partitionVoices(), 3))
+	for i := range expected {
+		if len(got[i]) != len(expected[i]) {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+		for j := range expected[i] {
+			if got[i][j] != expected[i][j] {
+				t.Fatalf("Expected %v, got %v", expected, got)
+			}
+		}
+	}
+}
+
+// with as many streams as notes ever sound at once, minPolyphony and dynamic never overlap notes
+func TestMonophonicPartitioners(t *testing.T) {
+	for _, p := range []partitioner{minPolyphony{}, dynamic{policy: stealOldest}} {
+		for i, s := range p.partition(partitionVoices(), 3) {
+			for j := 1; j < len(s.events); j++ {
+				prev := s.events[j-1]
+				if prev.rt+prev.dur > s.events[j].rt {
+					t.Errorf("%T: expected stream %d to play one note at a time, got %v", p, i, streamKeys([]stream{s}))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// statusMu guards what the clients report and what was sent to them, both change while the song plays
+var statusMu sync.Mutex
+
+// how often the clients are pinged to measure their round trip time
+const pingInterval = time.Second
+
+// rttPing marks the pings sent to measure the round trip time, the send time follows it
+var rttPing = []byte("rtt\x00")
+
+// pinger pings the clients until stop is closed, they echo the pings back to watch
+func pinger(clients []*client, send chan<- shared.Message, stop <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		ping := make(shared.PING_Packet, 32)
+		copy(ping, rttPing)
+		binary.BigEndian.PutUint64(ping[len(rttPing):], uint64(time.Now().UnixNano()))
+
+		for _, c := range clients {
+			send <- shared.Message{Pkt: &ping, Addr: c.addr}
+		}
+	}
+}
+
+// watch keeps track of what the clients report while the song plays, so the conductor can see which
+// machines are late, dropping notes or distorting
+func watch(recv <-chan shared.Message, clients []*client) {
+	for msg := range recv {
+		c := byAddr(clients, msg.Addr)
+		if c == nil {
+			continue
+		}
+
+		statusMu.Lock()
+		c.seen = time.Now()
+		statusMu.Unlock()
+
+		switch pkt := msg.Pkt.(type) {
+		case *shared.PING_Packet:
+			p := *pkt
+			if len(p) != 32 || !bytes.HasPrefix(p, rttPing) {
+				break
+			}
+
+			sent := time.Unix(0, int64(binary.BigEndian.Uint64(p[len(rttPing):])))
+
+			statusMu.Lock()
+			c.rtt = time.Since(sent)
+			statusMu.Unlock()
+		case *shared.LATENCY_Packet:
+			// the clients answer every ping with their latency, it rarely changes
+			statusMu.Lock()
+			changed := !c.hasLatency || c.latency != pkt.Latency
+			c.latency, c.hasLatency = pkt.Latency, true
+			statusMu.Unlock()
+
+			if changed {
+				logf("Client %v has output latency %v", c, pkt.Latency)
+			}
+		case *shared.CLIP_Packet:
+			statusMu.Lock()
+			c.clips += int(pkt.Clips)
+			clips := c.clips
+			statusMu.Unlock()
+
+			if pkt.Clips > 0 {
+				logf("Client %v clipped %d times, peaking at %+.1fdB (%d so far)", c, pkt.Clips, 20*math.Log10(float64(pkt.Peak)), clips)
+			}
+			if pkt.Stolen > 0 {
+				logf("Client %v cut %d notes short to stay under its voice limit", c, pkt.Stolen)
+			}
+		case *shared.STATUS_Packet:
+			statusMu.Lock()
+			prev := c.status
+			c.status = *pkt
+			// a report without notes keeps the slack last measured, such as the answer to QUIT
+			if !pkt.HasSlack {
+				c.status.Slack, c.status.HasSlack = prev.Slack, prev.HasSlack
+			}
+			statusMu.Unlock()
+
+			// only trouble is worth a line while the song plays
+			if pkt.Late > prev.Late || pkt.Dropped > prev.Dropped || pkt.Underruns > prev.Underruns {
+				logf("Client %v: %d late, %d dropped, %d underruns, slack %s", c, pkt.Late, pkt.Dropped, pkt.Underruns, pkt.SlackString())
+			}
+		}
+	}
+}
+
+// noteSent counts a note sent to the client
+func noteSent(c *client) {
+	statusMu.Lock()
+	c.sent++
+	statusMu.Unlock()
+}
+
+// how long to wait for the clients to answer QUIT with how the song went
+const finalStatusTimeout = 2 * time.Second
+
+// finish tells the clients the song is over and prints the status they answer with, the last one
+// they sent before is printed for the ones that don't answer in time
+func finish(send chan<- shared.Message, clients []*client) {
+	statusMu.Lock()
+	before := make([]int, len(clients))
+	for i, c := range clients {
+		before[i] = c.reports
+	}
+	statusMu.Unlock()
+
+	quit(send, clients)
+
+	deadline := time.Now().Add(finalStatusTimeout)
+	for time.Now().Before(deadline) && !reported(clients, before) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	printStatus(clients)
+}
+
+// reported tells whether every client sent more STATUS packets than it had sent before
+func reported(clients []*client, before []int) bool {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	for i, c := range clients {
+		if c.reports == before[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// printStatus prints the last status of every client, the clients that never reported are left out
+func printStatus(clients []*client) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	fmt.Printf("%-30s %8s %8s %8s %8s %9s %9s %10s\n", "Client", "Received", "Played", "Late", "Dropped", "Underruns", "Slack", "Offset")
+	for _, c := range clients {
+		s := c.status
+		if s == (shared.STATUS_Packet{}) {
+			continue
+		}
+
+		fmt.Printf("%-30s %8d %8d %8d %8d %9d %9s %10v\n", c, s.Received, s.Played, s.Late, s.Dropped, s.Underruns, s.SlackString(), s.Offset)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+func TestWatchLatency(t *testing.T) {
+	c := &client{addr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}, offset: 10 * time.Millisecond}
+
+	// the first answer to a ping was lost, a later one arrives while playing
+	recv := make(chan shared.Message, 1)
+	recv <- shared.Message{Pkt: &shared.LATENCY_Packet{Latency: 40 * time.Millisecond}, Addr: c.addr}
+	close(recv)
+	watch(recv, []*client{c})
+
+	if !c.hasLatency || c.compensation() != 50*time.Millisecond {
+		t.Errorf("Expected the client to be compensated by 50ms, got %v", c.compensation())
+	}
+}
+
+func TestWatchSlack(t *testing.T) {
+	c := &client{addr: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}}
+
+	// the answer to QUIT comes after the last note, it has no slack of its own
+	recv := make(chan shared.Message, 2)
+	recv <- shared.Message{Pkt: &shared.STATUS_Packet{Received: 10, Slack: 5 * time.Millisecond, HasSlack: true}, Addr: c.addr}
+	recv <- shared.Message{Pkt: &shared.STATUS_Packet{Received: 12}, Addr: c.addr}
+	close(recv)
+	watch(recv, []*client{c})
+
+	if c.status.Received != 12 || c.status.SlackString() != "5ms" {
+		t.Errorf("Expected 12 notes and the last slack measured, got %d and %s", c.status.Received, c.status.SlackString())
+	}
+
+	if s := (&shared.STATUS_Packet{}).SlackString(); s != "-" {
+		t.Errorf("Expected no slack to show as -, got %s", s)
+	}
+}
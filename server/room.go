@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// room describes where the clients sit, for example
+//
+//	{"clients": [
+//		{"identity": "3f2a", "nickname": "front-left", "x": 0, "y": 0},
+//		{"identity": "lab-laptop-3", "x": 1, "y": 0, "offset_ms": 40}
+//	]}
+//
+// Clients are arranged by order, then x, then y. So giving only x positions sweeps from left to
+// right, while giving only an order lets the room be described as a simple list.
+type room struct {
+	Clients []seat `json:"clients"`
+}
+
+// seat is the position of a single client
+type seat struct {
+	// the name the client was started with, or its hex encoded identity where any unique prefix will do
+	Identity string  `json:"identity"`
+	Nickname string  `json:"nickname"`
+	Order    int     `json:"order"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	// extra output latency in milliseconds on top of what the client reports, may be negative
+	Offset float64 `json:"offset_ms"`
+}
+
+func loadRoom(filename string) (*room, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &room{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if err := r.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return r, nil
+}
+
+// check makes sure no client can match two seats, which happens when two seats have the same
+// identity or one seat's hex prefix starts the other's
+func (r *room) check() error {
+	for i, a := range r.Clients {
+		for _, b := range r.Clients[i+1:] {
+			if a.Identity == "" || b.Identity == "" {
+				continue
+			}
+
+			short, long := strings.ToLower(a.Identity), strings.ToLower(b.Identity)
+			if len(short) > len(long) {
+				short, long = long, short
+			}
+
+			if short == long || isHex(short) && strings.HasPrefix(long, short) {
+				return fmt.Errorf("seats %q and %q could both be the same client", a.Identity, b.Identity)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// matches lists every seat the client could sit in, a nil room has no seats
+func (r *room) matches(c *client) []seat {
+	if r == nil {
+		return nil
+	}
+
+	id := hex.EncodeToString(c.identity[:])
+	name, _ := shared.IdentityName(c.identity)
+
+	var seats []seat
+	for _, s := range r.Clients {
+		if s.Identity == "" {
+			continue
+		}
+
+		if s.Identity == name || strings.HasPrefix(id, strings.ToLower(s.Identity)) {
+			seats = append(seats, s)
+		}
+	}
+
+	return seats
+}
+
+// find looks up the seat of a client. A client matching more than one seat has none, as it isn't
+// known which one it sits in.
+func (r *room) find(c *client) (seat, bool) {
+	seats := r.matches(c)
+	if len(seats) != 1 {
+		return seat{}, false
+	}
+
+	return seats[0], true
+}
+
+// arrange sorts the clients by their seats, so stream i is played by the i-th client in the room.
+// Clients that aren't in the room keep their discovery order after every seated client.
+func (r *room) arrange(clients []*client) {
+	seats := make(map[*client]seat)
+	for _, c := range clients {
+		if s, ok := r.find(c); ok {
+			seats[c] = s
+		} else {
+			fmt.Println("Client", c, "has no seat in the room")
+		}
+	}
+
+	sort.SliceStable(clients, func(i, j int) bool {
+		a, aok := seats[clients[i]]
+		b, bok := seats[clients[j]]
+
+		switch {
+		case !aok || !bok:
+			return aok && !bok
+		case a.Order != b.Order:
+			return a.Order < b.Order
+		case a.X != b.X:
+			return a.X < b.X
+		default:
+			return a.Y < b.Y
+		}
+	})
+}
+
+// place sets the pan of every seated client from its x position, the leftmost seat is -1 and the
+// rightmost 1. Clients without a seat, or a room where everyone has the same x, stay in the middle.
+func (r *room) place(clients []*client) {
+	seats := make(map[*client]seat)
+	for _, c := range clients {
+		if s, ok := r.find(c); ok {
+			seats[c] = s
+		}
+	}
+
+	first := true
+	var left, right float64
+	for _, s := range seats {
+		if first || s.X < left {
+			left = s.X
+		}
+		if first || s.X > right {
+			right = s.X
+		}
+		first = false
+	}
+
+	for _, c := range clients {
+		s, ok := seats[c]
+		if !ok || left == right {
+			c.pan = 0
+			continue
+		}
+
+		c.pan = float32(2*(s.X-left)/(right-left) - 1)
+	}
+}
+
+// moving passes every part of the song on to the next seat every so often, so the voices travel
+// around the room. The last seat hands its part back to the first, and a note that is still
+// sounding when its part moves on is finished where it started.
+type moving struct {
+	partitioner
+	every time.Duration
+}
+
+func (m moving) partition(voices []*voice, n int) []stream {
+	parts := m.partitioner.partition(voices, n)
+
+	streams := make([]stream, len(parts))
+	for i, part := range parts {
+		for _, event := range part.events {
+			j := (i + int(event.rt/m.every)) % len(parts)
+			streams[j].events = append(streams[j].events, event)
+			streams[j].totalOnTime += event.dur
+		}
+	}
+
+	for i := range streams {
+		sortEvents(streams[i].events)
+	}
+
+	return streams
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+func TestRoomPlace(t *testing.T) {
+	clients := make([]*client, 4)
+	for i := range clients {
+		clients[i] = &client{}
+		clients[i].identity[0] = byte(i + 1)
+	}
+	id := func(c *client) string {
+		return hex.EncodeToString(c.identity[:1])
+	}
+
+	// the last client has no seat
+	r := &room{Clients: []seat{
+		{Identity: id(clients[0]), X: 4},
+		{Identity: id(clients[1]), X: 0},
+		{Identity: id(clients[2]), X: 2},
+	}}
+	r.place(clients)
+
+	expected := []float32{1, -1, 0, 0}
+	for i, c := range clients {
+		if c.pan != expected[i] {
+			t.Errorf("Client %d: expected pan %f, got %f", i, expected[i], c.pan)
+		}
+	}
+}
+
+func TestRoomFind(t *testing.T) {
+	random := &client{identity: [24]byte{0x3f, 0x2a, 0x01}}
+	named := &client{}
+	named.identity, _ = shared.NameIdentity("lab-laptop-3")
+	unseated := &client{identity: [24]byte{0x99}}
+
+	r := &room{Clients: []seat{
+		// a seat without an identity matches nobody
+		{Nickname: "empty"},
+		{Identity: "3F2A", Nickname: "front-left"},
+		{Identity: "lab-laptop-3", Nickname: "back"},
+	}}
+
+	tests := []struct {
+		c        *client
+		nickname string
+		ok       bool
+	}{
+		{random, "front-left", true},
+		{named, "back", true},
+		{unseated, "", false},
+	}
+
+	for _, test := range tests {
+		s, ok := r.find(test.c)
+		if ok != test.ok || s.Nickname != test.nickname {
+			t.Errorf("Expected %x to find seat %q (%v), got %q (%v)", test.c.identity[:2], test.nickname, test.ok, s.Nickname, ok)
+		}
+	}
+
+	var none *room
+	if _, ok := none.find(random); ok {
+		t.Error("Expected no seats without a room")
+	}
+
+	// a client matching two seats sits in neither
+	r.Clients = append(r.Clients, seat{Identity: "3f", Nickname: "front"})
+	if s, ok := r.find(random); ok {
+		t.Errorf("Expected no seat for a client matching two, got %q", s.Nickname)
+	}
+}
+
+func TestRoomCheck(t *testing.T) {
+	tests := []struct {
+		a, b string
+		ok   bool
+	}{
+		{"3f2a", "3F", false},
+		{"3f2a", "3f2a", false},
+		{"lab-laptop-3", "lab-laptop-3", false},
+		// names only match whole, so one starting the other is fine
+		{"lab-laptop-3", "lab-laptop-30", true},
+		{"3f2a", "3f2b", true},
+		{"3f2a", "", true},
+	}
+
+	for _, test := range tests {
+		r := &room{Clients: []seat{{Identity: test.a}, {Identity: test.b}}}
+		if err := r.check(); (err == nil) != test.ok {
+			t.Errorf("Seats %q and %q: expected ok %v, got %v", test.a, test.b, test.ok, err)
+		}
+	}
+}
+
+func TestRoomArrange(t *testing.T) {
+	clients := make([]*client, 5)
+	for i := range clients {
+		clients[i] = &client{nickname: string(rune('a' + i))}
+		clients[i].identity[0] = byte(i + 1)
+	}
+
+	// the first and last clients have no seat, the rest are sorted by order then x
+	r := &room{Clients: []seat{
+		{Identity: "02", Order: 2},
+		{Identity: "03", X: 1},
+		{Identity: "04", X: 0},
+	}}
+	r.arrange(clients)
+
+	expected := "dcbae"
+	got := ""
+	for _, c := range clients {
+		got += c.nickname
+	}
+	if got != expected {
+		t.Errorf("Expected the clients in the order %s, got %s", expected, got)
+	}
+}
+
+// fixedParts hands out the same parts every time
+type fixedParts []stream
+
+func (f fixedParts) partition(voices []*voice, n int) []stream {
+	return append([]stream(nil), f...)
+}
+
+func TestMoving(t *testing.T) {
+	part := func(first uint8) stream {
+		var s stream
+		for i := 0; i < 4; i++ {
+			s.events = append(s.events, streamEvent{key: first + uint8(i), rt: time.Duration(i) * time.Second, dur: time.Second})
+		}
+		return s
+	}
+
+	// every second each part moves on to the other client
+	m := moving{partitioner: fixedParts{part(60), part(70)}, every: time.Second}
+	expected := [][]uint8{{60, 71, 62, 73}, {70, 61, 72, 63}}
+
+	for i, s := range m.partition(nil, 2) {
+		if len(s.events) != len(expected[i]) {
+			t.Fatalf("Client %d: expected %d notes, got %d", i, len(expected[i]), len(s.events))
+		}
+		for j, event := range s.events {
+			if event.key != expected[i][j] {
+				t.Errorf("Client %d note %d: expected key %d, got %d", i, j, expected[i][j], event.key)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// song is a MIDI file split into a stream for every client
+type song struct {
+	name     string
+	streams  []stream
+	duration time.Duration
+}
+
+// loadSong reads the file and splits it into n streams, every client plays speakers of them
+func loadSong(filename string, p partitioner, drums drumRouting, n, speakers int) (*song, error) {
+	voices, err := makeIV(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	streams, err := drums.partition(p, voices, n)
+	if err != nil {
+		return nil, err
+	}
+
+	countSounding(streams, speakers)
+
+	s := &song{name: filepath.Base(filename), streams: streams, duration: songDuration(streams)}
+	fmt.Println("Duration:", s.duration)
+	report(voices, streams, drums)
+
+	return s, nil
+}
+
+// playSong plays the song on the clients by the transport's clock, until it ends or is stopped
+func playSong(s *song, clients []*client, send chan<- shared.Message, v *voicing, tr *transport) {
+	wg := &sync.WaitGroup{}
+	for i, c := range clients {
+		wg.Add(1)
+		go func(c *client, events []streamEvent) {
+			defer wg.Done()
+			perform(c, events, v, tr, send)
+		}(c, s.streams[i].events)
+	}
+
+	// the song is over once its last note ended, wherever the transport moved in between
+	for {
+		c := tr.now()
+		if c.done || c.position(time.Now()) >= s.duration {
+			break
+		}
+
+		select {
+		case <-c.changed:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	tr.stop(false)
+	wg.Wait()
+}
+
+// perform sends the notes of a stream to the client as the transport reaches them, until the song is done
+func perform(c *client, events []streamEvent, v *voicing, tr *transport, send chan<- shared.Message) {
+	// notes that may still be sounding on the client, they are stopped when the song is paused or moved
+	var sounding []streamEvent
+	silence := func() {
+		for _, event := range sounding {
+			send <- shared.Message{Pkt: stopKey(event.key), Addr: c.addr}
+		}
+		sounding = nil
+	}
+
+	seeks := 0
+	i := 0
+	for {
+		clk := tr.now()
+		if clk.done {
+			silence()
+			return
+		}
+
+		if clk.seeks != seeks {
+			seeks = clk.seeks
+			silence()
+			i = sort.Search(len(events), func(j int) bool { return events[j].rt >= clk.pos })
+		}
+
+		if clk.paused {
+			silence()
+		}
+
+		// nothing to do until the transport changes, a seek back may still bring notes
+		if clk.paused || i >= len(events) {
+			<-clk.changed
+			continue
+		}
+
+		event := events[i]
+
+		// Sleep until the event is due
+		due := clk.when(event.rt).Add(-c.compensation())
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-timer.C:
+		case <-clk.changed:
+			timer.Stop()
+			continue
+		}
+
+		// notes are held for as long as they last at the current tempo
+		event.dur = time.Duration(float64(event.dur) / clk.tempo)
+
+		send <- shared.Message{
+			Pkt:  stamp(v.packet(c, event), due),
+			Addr: c.addr,
+		}
+		noteSent(c)
+		i++
+
+		if event.channel == percussionChannel {
+			continue
+		}
+
+		playing := sounding[:0]
+		for _, e := range sounding {
+			if e.rt+e.dur > event.rt {
+				playing = append(playing, e)
+			}
+		}
+		sounding = append(playing, events[i-1])
+	}
+}
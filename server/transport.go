@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// limits of the tempo, as a multiple of the song's own
+const (
+	minTempo = 0.25
+	maxTempo = 4
+)
+
+// transport is the clock a song is played by. It can be paused, moved to another position and
+// played faster or slower while the clients wait on it for their notes.
+type transport struct {
+	mu sync.Mutex
+	c  clock
+}
+
+// clock is the state of the transport at one moment
+type clock struct {
+	// the song was at pos at the time at, and has moved tempo times as fast as the wall clock since
+	pos    time.Duration
+	at     time.Time
+	tempo  float64
+	paused bool
+
+	// seeks counts the times the position jumped, so the players know to find their place again
+	seeks int
+	// done ends the song early, quit also ends the ones after it
+	done, quit bool
+
+	// changed is closed when the transport changes, the clock is out of date from then on
+	changed chan struct{}
+}
+
+// newTransport starts the song at the wall time start
+func newTransport(start time.Time) *transport {
+	return &transport{c: clock{at: start, tempo: 1, changed: make(chan struct{})}}
+}
+
+// now is the current state of the transport
+func (t *transport) now() clock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.c
+}
+
+// position is how far into the song the clock is at the wall time now, negative before it starts
+func (c clock) position(now time.Time) time.Duration {
+	if c.paused {
+		return c.pos
+	}
+	return c.pos + time.Duration(float64(now.Sub(c.at))*c.tempo)
+}
+
+// when is the wall time the song reaches rt, unless the transport changes before then
+func (c clock) when(rt time.Duration) time.Time {
+	return c.at.Add(time.Duration(float64(rt-c.pos) / c.tempo))
+}
+
+// update changes the transport and wakes everyone waiting on it
+func (t *transport) update(f func(c *clock)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.c.pos, t.c.at = t.c.position(now), now
+	f(&t.c)
+
+	close(t.c.changed)
+	t.c.changed = make(chan struct{})
+}
+
+func (t *transport) togglePause() {
+	t.update(func(c *clock) { c.paused = !c.paused })
+}
+
+// seek moves the song by d, it can't go back past the start
+func (t *transport) seek(d time.Duration) {
+	t.update(func(c *clock) {
+		c.pos += d
+		if c.pos < 0 {
+			c.pos = 0
+		}
+		c.seeks++
+	})
+}
+
+// setTempo changes the tempo by d, 0.05 is 5% of the song's own
+func (t *transport) setTempo(d float64) {
+	t.update(func(c *clock) {
+		c.tempo += d
+		if c.tempo < minTempo {
+			c.tempo = minTempo
+		}
+		if c.tempo > maxTempo {
+			c.tempo = maxTempo
+		}
+	})
+}
+
+// resetTempo goes back to the song's own tempo
+func (t *transport) resetTempo() {
+	t.update(func(c *clock) { c.tempo = 1 })
+}
+
+// stop ends the song, quit ends every song after it too
+func (t *transport) stop(quit bool) {
+	t.update(func(c *clock) {
+		c.done = true
+		c.quit = c.quit || quit
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	start := time.Now().Add(time.Hour)
+	tr := newTransport(start)
+
+	c := tr.now()
+	if pos := c.position(start.Add(time.Second)); pos != time.Second {
+		t.Errorf("Expected to be 1s in, got %v", pos)
+	}
+	if at := c.when(2 * time.Second); !at.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected 2s to be reached 2s after the start, got %v", at.Sub(start))
+	}
+
+	// twice as fast reaches the rest of the song in half the time
+	tr.setTempo(1)
+	c2 := tr.now()
+	select {
+	case <-c.changed:
+	default:
+		t.Error("Expected the old clock to be told about the change")
+	}
+
+	pos := c2.position(c2.at)
+	if at := c2.when(pos + 2*time.Second); at.Sub(c2.at) != time.Second {
+		t.Errorf("Expected 2s of song to take 1s at double tempo, took %v", at.Sub(c2.at))
+	}
+
+	tr.setTempo(10)
+	if tempo := tr.now().tempo; tempo != maxTempo {
+		t.Errorf("Expected the tempo to stop at %v, got %v", maxTempo, tempo)
+	}
+
+	// paused, the position stands still
+	tr.togglePause()
+	c3 := tr.now()
+	if c3.position(c3.at.Add(time.Minute)) != c3.pos {
+		t.Error("Expected the position to stand still while paused")
+	}
+
+	// seeking back past the start stops at the start
+	seeks := c3.seeks
+	tr.seek(-24 * time.Hour)
+	c4 := tr.now()
+	if c4.pos != 0 || c4.seeks != seeks+1 {
+		t.Errorf("Expected to be at the start after 1 more seek, got %v after %d", c4.pos, c4.seeks-seeks)
+	}
+
+	tr.stop(false)
+	if c := tr.now(); !c.done || c.quit {
+		t.Error("Expected the song to be done without quitting")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Alextopher/itl-chorus/shared"
+)
+
+// panSource decides where the clients place notes between their speakers
+type panSource int
+
+const (
+	panMIDI  panSource = iota // follow the pan controller (CC10) of the song
+	panRoom                   // place every note where its client sits in the room, left to right
+	panNone                   // play everything in the middle
+	panSplit                  // give every client two streams, one for each speaker
+)
+
+func parsePanSource(s string) (panSource, error) {
+	switch s {
+	case "midi":
+		return panMIDI, nil
+	case "room":
+		return panRoom, nil
+	case "none":
+		return panNone, nil
+	case "split":
+		return panSplit, nil
+	default:
+		return 0, fmt.Errorf("unknown pan %q (expected midi, room, none or split)", s)
+	}
+}
+
+// voicing decides how the notes of the song sound on the clients
+type voicing struct {
+	// name of the timbre the clients play
+	timbre string
+
+	// envelope replaces the envelope of the client's voice when it isn't nil
+	envelope *shared.Envelope
+
+	pan panSource
+
+	dynamics *dynamics
+}
+
+// stereoPairs lists every client twice, so each is handed two streams next to each other
+func stereoPairs(clients []*client) []*client {
+	pairs := make([]*client, 0, 2*len(clients))
+	for _, c := range clients {
+		pairs = append(pairs, c, c)
+	}
+	return pairs
+}
+
+// splitStereo places the even streams on the left speaker and the odd ones on the right, so the
+// two streams of a pair from stereoPairs don't share a speaker
+func splitStereo(streams []stream) {
+	for i, s := range streams {
+		pan := float32(-1)
+		if i%2 == 1 {
+			pan = 1
+		}
+
+		for j := range s.events {
+			s.events[j].pan = pan
+		}
+	}
+}
+
+// notePan is where the client places the note between its speakers
+func (v *voicing) notePan(c *client, event streamEvent) float32 {
+	switch v.pan {
+	case panRoom:
+		return c.pan
+	case panNone:
+		return 0
+	default:
+		return event.pan
+	}
+}
+
+// play turns a note into a PLAY packet for the client
+func (v *voicing) play(c *client, event streamEvent) *shared.PLAY_Packet {
+	return &shared.PLAY_Packet{
+		Duration:   event.dur,
+		Frequency:  midiNoteToFreq(event.key),
+		Key:        event.key,
+		Amplitude:  v.dynamics.amplitude(event),
+		Pan:        v.notePan(c, event),
+		Voice:      c.voice(v.timbre),
+		Envelope:   v.envelope,
+		Instrument: &shared.Instrument{Program: event.program, Velocity: event.vel},
+	}
+}
+
+// stopKey is the STOP packet that releases the note playing key
+func stopKey(key uint8) *shared.STOP_Packet {
+	return &shared.STOP_Packet{Frequency: midiNoteToFreq(key), Key: key}
+}
+
+// drum turns a hit on the percussion channel into a DRUM packet for the client
+func (v *voicing) drum(c *client, event streamEvent) *shared.DRUM_Packet {
+	return &shared.DRUM_Packet{
+		Duration:  event.dur,
+		Key:       event.key,
+		Velocity:  event.vel,
+		Amplitude: v.dynamics.amplitude(event),
+		Pan:       v.notePan(c, event),
+	}
+}
+
+// packet turns any note of the song into the packet for the client
+func (v *voicing) packet(c *client, event streamEvent) shared.Packet {
+	if event.channel == percussionChannel {
+		return v.drum(c, event)
+	}
+	return v.play(c, event)
+}
+
+// stamp records when the packet is meant to be sent, so the client can tell how late it arrives
+func stamp(pkt shared.Packet, due time.Time) shared.Packet {
+	switch p := pkt.(type) {
+	case *shared.PLAY_Packet:
+		p.Due = shared.Timestamp(due)
+	case *shared.DRUM_Packet:
+		p.Due = shared.Timestamp(due)
+	}
+	return pkt
+}
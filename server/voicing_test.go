@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitStereo(t *testing.T) {
+	clients := []*client{{nickname: "a"}, {nickname: "b"}}
+	v := &voicing{pan: panSplit, dynamics: &dynamics{curve: &velocityCurve{}}}
+
+	streams := make([]stream, 4)
+	for i := range streams {
+		streams[i].events = []streamEvent{{key: uint8(60 + i), dur: time.Second, pan: 0.3}}
+	}
+	splitStereo(streams)
+
+	// every client carries two streams, one on each speaker
+	players := stereoPairs(clients)
+	expected := []float32{-1, 1, -1, 1}
+	for i, s := range streams {
+		if players[i] != clients[i/2] {
+			t.Errorf("Expected stream %d to be played by client %d", i, i/2)
+		}
+
+		if pan := v.play(players[i], s.events[0]).Pan; pan != expected[i] {
+			t.Errorf("Stream %d: expected pan %v, got %v", i, expected[i], pan)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+)
+
+// An Identity is the 24 bytes a client sends in its CAPS packets.
+// It is either random or a human readable name padded with zeros.
+
+// NameIdentity turns a name into an identity
+func NameIdentity(name string) ([24]byte, error) {
+	var id [24]byte
+	if name == "" || len(name) > len(id) {
+		return id, fmt.Errorf("name %q must be between 1 and %d bytes", name, len(id))
+	}
+
+	for _, r := range name {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return id, fmt.Errorf("name %q must be printable ascii", name)
+		}
+	}
+
+	copy(id[:], name)
+	return id, nil
+}
+
+// IdentityName recovers the name from an identity made by NameIdentity
+func IdentityName(id [24]byte) (string, bool) {
+	name := string(bytes.TrimRight(id[:], "\x00"))
+	if name == "" {
+		return "", false
+	}
+
+	for _, r := range name {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return "", false
+		}
+	}
+
+	return name, true
+}
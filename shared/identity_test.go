@@ -0,0 +1,24 @@
+package shared
+
+import "testing"
+
+func TestIdentityName(t *testing.T) {
+	id, err := NameIdentity("lab-laptop-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, ok := IdentityName(id)
+	if !ok || name != "lab-laptop-3" {
+		t.Errorf("Expected name %q, got %q", "lab-laptop-3", name)
+	}
+
+	if _, err := NameIdentity("a name that is far too long to fit"); err == nil {
+		t.Error("Expected an error for a long name")
+	}
+
+	// random identities have no name
+	if _, ok := IdentityName([24]byte{0x9b, 0x01, 0xff}); ok {
+		t.Error("Expected no name for a random identity")
+	}
+}
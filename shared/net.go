@@ -73,6 +73,20 @@ func Recv(conn *net.UDPConn, ch chan<- Message) {
 			p = &PLAY_Packet{}
 		case CAPS:
 			p = &CAPS_Packet{}
+		case STOP:
+			p = &STOP_Packet{}
+		case LATENCY:
+			p = &LATENCY_Packet{}
+		case TIMBRE:
+			p = &TIMBRE_Packet{}
+		case DRUM:
+			p = &DRUM_Packet{}
+		case PARAM:
+			p = &PARAM_Packet{}
+		case CLIP:
+			p = &CLIP_Packet{}
+		case STATUS:
+			p = &STATUS_Packet{}
 		default:
 			p = &UNKNOWN_Packet{}
 		}
@@ -5,7 +5,10 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,8 +18,15 @@ const (
 	KA PacketType = iota // Keep Alive
 	PING
 	QUIT
-	PLAY    // [0] uint duration seconds [1] uint nanoseconds offest [2] frequency [3] Amplitude [4] Voice
+	PLAY    // [0] uint duration seconds, key [1] uint nanoseconds offest [2] frequency [3] Amplitude [4] Voice [5] flags [6] envelope [7] instrument
 	CAPS    // [0] name [1] number of voices [2-7] identity
+	STOP    // [0] frequency [1] key
+	LATENCY // [0] uint seconds [1] uint nanoseconds
+	TIMBRE  // [0] voice id [1-7] name
+	DRUM    // [0] uint duration seconds [1] uint nanoseconds [2] key, velocity [3] amplitude
+	PARAM   // [0] voice id [1] value [2-7] effect parameter
+	CLIP    // [0] clips [1] peak [2] notes cut short
+	STATUS  // [0] received [1] played [2] late [3] dropped [4] slack [5] underruns [6] clock offset
 	UNKNOWN = 0xFFFFFFFF
 )
 
@@ -74,12 +84,12 @@ func (p PING_Packet) Serialize() []byte {
 	return p
 }
 
-func (p PING_Packet) DeSerialize(data []byte) error {
+func (p *PING_Packet) DeSerialize(data []byte) error {
 	if len(data) != 32 {
 		return fmt.Errorf("invalid PING_Packet data length %d byte", len(data))
 	}
 
-	p = data
+	*p = append(PING_Packet(nil), data...)
 	return nil
 }
 
@@ -114,31 +124,130 @@ func (QUIT_Packet) String() string {
 }
 
 // Play Packet (PLAY)
-// [0-3] uint32 duration in seconds
+// [0-2] uint24 duration in seconds
+// [3] uint8 MIDI key, a STOP for the key releases the note
 // [4-7] uint32 duration in nanoseconds
 // [8-11] uint32 frequency
 // [12-15] float32 amplitude
 // [16-19] uint32 voice id
-// [20-31] unused
+// [20] uint8 flags
+// [21-24] uint8 envelope attack, decay, sustain and release, used when the envelope flag is set
+// [25] int8 pan from -127 (left) to 127 (right)
+// [26-27] uint8 instrument program and velocity, used when the instrument flag is set
+// [28-31] uint32 timestamp the server meant to send the packet at
 type PLAY_Packet struct {
 	Duration  time.Duration
 	Frequency uint32
 	Amplitude float32
 	Voice     uint32
+
+	// Key is the MIDI key of the note. Frequencies are whole numbers, low keys share them, so STOP names the key instead.
+	Key uint8
+
+	// Due is the Timestamp the server meant to send the packet at, so clients can tell how late it arrived.
+	// It is 0 when unknown.
+	Due uint32
+
+	// Pan is where the note sits between -1 (left) and 1 (right), for clients with stereo speakers
+	Pan float32
+
+	// Envelope overrides the envelope of the voice when it isn't nil
+	Envelope *Envelope
+
+	// Instrument is what the note is played on in the song, clients that can play it use it instead of the voice
+	Instrument *Instrument
 }
 
-var padding []byte = make([]byte, 12)
+// Instrument is a General MIDI program and the velocity the note was played with,
+// instruments sampled at several velocities sound different when played harder
+type Instrument struct {
+	Program  uint8
+	Velocity uint8
+}
+
+// Envelope describes how a note fades in and out.
+// Times are sent in steps of 10ms up to 2.55s, the sustain level in steps of 1/255.
+type Envelope struct {
+	Attack  time.Duration
+	Decay   time.Duration
+	Sustain float32
+	Release time.Duration
+}
+
+// ParseEnvelope parses an envelope written as "attack,decay,sustain,release", for example "10ms,100ms,0.7,200ms"
+func ParseEnvelope(s string) (Envelope, error) {
+	var e Envelope
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return e, fmt.Errorf("envelope %q must be attack,decay,sustain,release", s)
+	}
+
+	var err error
+	times := []*time.Duration{&e.Attack, &e.Decay, nil, &e.Release}
+	for i, t := range times {
+		if t == nil {
+			continue
+		}
+
+		*t, err = time.ParseDuration(strings.TrimSpace(parts[i]))
+		if err != nil {
+			return e, fmt.Errorf("envelope %q: %w", s, err)
+		}
+	}
+
+	sustain, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 32)
+	if err != nil || sustain < 0 || sustain > 1 {
+		return e, fmt.Errorf("envelope %q: sustain must be between 0 and 1", s)
+	}
+	e.Sustain = float32(sustain)
+
+	return e, nil
+}
+
+// PLAY flags
+const (
+	playEnvelope   uint8 = 1 << iota // the packet carries an envelope
+	playInstrument                   // the packet carries an instrument
+)
+
+const envelopeStep = time.Millisecond * 10
+
+// maxPlaySeconds is the longest duration in seconds that fits next to the key
+const maxPlaySeconds = 1<<24 - 1
+
+// Timestamp is t in milliseconds since the Unix epoch, wrapped to 32 bits. Subtracting two timestamps
+// as an int32 gives the time between them, the wrap doesn't matter as long as they are close.
+func Timestamp(t time.Time) uint32 {
+	return uint32(t.UnixNano() / int64(time.Millisecond))
+}
 
 func (*PLAY_Packet) Type() PacketType {
 	return PLAY
 }
 
+// envelopeTime fits a duration in a single byte
+func envelopeTime(d time.Duration) uint8 {
+	steps := (d + envelopeStep/2) / envelopeStep
+	if steps > math.MaxUint8 {
+		return math.MaxUint8
+	}
+	if steps < 0 {
+		return 0
+	}
+	return uint8(steps)
+}
+
 func (p *PLAY_Packet) Serialize() []byte {
 	// Create a buffer
 	buf := bytes.Buffer{}
 
-	// Write the duration
-	binary.Write(&buf, binary.BigEndian, uint32(p.Duration/time.Second))
+	// Write the duration and the key
+	seconds := p.Duration / time.Second
+	if seconds > maxPlaySeconds {
+		seconds = maxPlaySeconds
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(seconds)<<8|uint32(p.Key))
 	binary.Write(&buf, binary.BigEndian, uint32(p.Duration%time.Second))
 
 	// Write the frequency
@@ -150,8 +259,32 @@ func (p *PLAY_Packet) Serialize() []byte {
 	// Write the voice
 	binary.Write(&buf, binary.BigEndian, p.Voice)
 
-	// Write 12 bytes of padding
-	buf.Write(padding)
+	// Write the flags and the envelope
+	var flags uint8
+	envelope := make([]byte, 4)
+	if p.Envelope != nil {
+		flags |= playEnvelope
+		envelope[0] = envelopeTime(p.Envelope.Attack)
+		envelope[1] = envelopeTime(p.Envelope.Decay)
+		envelope[2] = uint8(math.Round(math.Max(0, math.Min(1, float64(p.Envelope.Sustain))) * math.MaxUint8))
+		envelope[3] = envelopeTime(p.Envelope.Release)
+	}
+	instrument := make([]byte, 2)
+	if p.Instrument != nil {
+		flags |= playInstrument
+		instrument[0] = p.Instrument.Program
+		instrument[1] = p.Instrument.Velocity
+	}
+	buf.WriteByte(flags)
+	buf.Write(envelope)
+
+	// Write the pan and the instrument
+	pan := math.Round(math.Max(-1, math.Min(1, float64(p.Pan))) * math.MaxInt8)
+	buf.WriteByte(byte(int8(pan)))
+	buf.Write(instrument)
+
+	// Write the timestamp
+	binary.Write(&buf, binary.BigEndian, p.Due)
 
 	// Return the buffer
 	return buf.Bytes()
@@ -168,12 +301,13 @@ func (p *PLAY_Packet) DeSerialize(data []byte) error {
 	// Write the data
 	buf.Write(data)
 
-	// Read the seconds part of the duration
+	// Read the seconds part of the duration and the key
 	var seconds, nanoseconds uint32
 	binary.Read(&buf, binary.BigEndian, &seconds)
 	binary.Read(&buf, binary.BigEndian, &nanoseconds)
 
-	p.Duration = time.Duration(seconds)*time.Second + time.Duration(nanoseconds)
+	p.Duration = time.Duration(seconds>>8)*time.Second + time.Duration(nanoseconds)
+	p.Key = uint8(seconds)
 
 	// Read the frequency
 	binary.Read(&buf, binary.BigEndian, &p.Frequency)
@@ -184,11 +318,45 @@ func (p *PLAY_Packet) DeSerialize(data []byte) error {
 	// Read the voice
 	binary.Read(&buf, binary.BigEndian, &p.Voice)
 
+	// Read the flags and the envelope
+	flags, _ := buf.ReadByte()
+	envelope := buf.Next(4)
+	pan, _ := buf.ReadByte()
+	instrument := buf.Next(2)
+	binary.Read(&buf, binary.BigEndian, &p.Due)
+
+	p.Envelope = nil
+	if flags&playEnvelope != 0 {
+		p.Envelope = &Envelope{
+			Attack:  time.Duration(envelope[0]) * envelopeStep,
+			Decay:   time.Duration(envelope[1]) * envelopeStep,
+			Sustain: float32(envelope[2]) / math.MaxUint8,
+			Release: time.Duration(envelope[3]) * envelopeStep,
+		}
+	}
+
+	p.Pan = float32(int8(pan)) / math.MaxInt8
+
+	p.Instrument = nil
+	if flags&playInstrument != 0 {
+		p.Instrument = &Instrument{Program: instrument[0], Velocity: instrument[1]}
+	}
+
 	return nil
 }
 
 func (p *PLAY_Packet) String() string {
-	return fmt.Sprintf("PLAY(%d, %d, %f, %d)", p.Duration, p.Frequency, p.Amplitude, p.Voice)
+	s := fmt.Sprintf("PLAY(%d, %d, %f, %d, key %d", p.Duration, p.Frequency, p.Amplitude, p.Voice, p.Key)
+	if p.Pan != 0 {
+		s += fmt.Sprintf(", pan %.2f", p.Pan)
+	}
+	if p.Envelope != nil {
+		s += fmt.Sprintf(", %v", *p.Envelope)
+	}
+	if p.Instrument != nil {
+		s += fmt.Sprintf(", %v", *p.Instrument)
+	}
+	return s + ")"
 }
 
 // Caps Packet (CAPS)
@@ -258,6 +426,406 @@ func (p *CAPS_Packet) String() string {
 	return fmt.Sprintf("CAPS(%q, %d, %s)", p.Name, p.NumVoices, hex.EncodeToString(p.Identity[:]))
 }
 
+// Stop Packet (STOP)
+// [0-3] uint32 frequency of the note to silence
+// [4] uint8 MIDI key of the note to silence
+// [5-31] unused
+type STOP_Packet struct {
+	Frequency uint32
+	Key       uint8
+}
+
+func (*STOP_Packet) Type() PacketType {
+	return STOP
+}
+
+func (p *STOP_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	// Write the frequency and the key
+	binary.Write(&buf, binary.BigEndian, p.Frequency)
+	buf.WriteByte(p.Key)
+
+	// Write 27 bytes of padding
+	buf.Write(make([]byte, 27))
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *STOP_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid STOP_Packet data length %d byte", len(data))
+	}
+
+	p.Frequency = binary.BigEndian.Uint32(data[0:4])
+	p.Key = data[4]
+	return nil
+}
+
+func (p *STOP_Packet) String() string {
+	return fmt.Sprintf("STOP(%d, key %d)", p.Frequency, p.Key)
+}
+
+// Latency Packet (LATENCY)
+// Sent by a client to tell the server how long its audio takes to come out of the speakers
+// [0-3] uint32 latency in seconds
+// [4-7] uint32 latency in nanoseconds
+// [8-31] unused
+type LATENCY_Packet struct {
+	Latency time.Duration
+}
+
+func (*LATENCY_Packet) Type() PacketType {
+	return LATENCY
+}
+
+func (p *LATENCY_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	// Write the latency
+	binary.Write(&buf, binary.BigEndian, uint32(p.Latency/time.Second))
+	binary.Write(&buf, binary.BigEndian, uint32(p.Latency%time.Second))
+
+	// Write 24 bytes of padding
+	buf.Write(make([]byte, 24))
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *LATENCY_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid LATENCY_Packet data length %d byte", len(data))
+	}
+
+	buf := bytes.NewReader(data)
+
+	var seconds, nanoseconds uint32
+	binary.Read(buf, binary.BigEndian, &seconds)
+	binary.Read(buf, binary.BigEndian, &nanoseconds)
+
+	p.Latency = time.Duration(seconds)*time.Second + time.Duration(nanoseconds)
+
+	return nil
+}
+
+func (p *LATENCY_Packet) String() string {
+	return fmt.Sprintf("LATENCY(%v)", p.Latency)
+}
+
+// Timbre Packet (TIMBRE)
+// Sent by a client for every timbre it can play, so the server can choose voices by name
+// [0-3] uint32 voice id
+// [4-31] name, padded with zeros
+type TIMBRE_Packet struct {
+	Voice uint32
+	Name  string
+}
+
+func (*TIMBRE_Packet) Type() PacketType {
+	return TIMBRE
+}
+
+func (p *TIMBRE_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	// Write the voice
+	binary.Write(&buf, binary.BigEndian, p.Voice)
+
+	// Write the name, cut or padded to 28 bytes
+	name := make([]byte, 28)
+	copy(name, p.Name)
+	buf.Write(name)
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *TIMBRE_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid TIMBRE_Packet data length %d byte", len(data))
+	}
+
+	p.Voice = binary.BigEndian.Uint32(data[0:4])
+	p.Name = string(bytes.TrimRight(data[4:], "\x00"))
+
+	return nil
+}
+
+func (p *TIMBRE_Packet) String() string {
+	return fmt.Sprintf("TIMBRE(%d, %q)", p.Voice, p.Name)
+}
+
+// Drum Packet (DRUM)
+// A hit on the General MIDI percussion channel, the key picks the drum instead of a pitch
+// [0-3] uint32 duration in seconds
+// [4-7] uint32 duration in nanoseconds
+// [8] uint8 key
+// [9] uint8 velocity
+// [10-13] float32 amplitude
+// [14] int8 pan
+// [15-18] uint32 timestamp the server meant to send the packet at
+// [19-31] unused
+type DRUM_Packet struct {
+	Duration  time.Duration
+	Key       uint8
+	Velocity  uint8
+	Amplitude float32
+	// Pan is where the hit sits between -1 (left) and 1 (right), like PLAY_Packet.Pan
+	Pan float32
+	// Due is when the server meant to send the packet, like PLAY_Packet.Due
+	Due uint32
+}
+
+func (*DRUM_Packet) Type() PacketType {
+	return DRUM
+}
+
+func (p *DRUM_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	// Write the duration
+	binary.Write(&buf, binary.BigEndian, uint32(p.Duration/time.Second))
+	binary.Write(&buf, binary.BigEndian, uint32(p.Duration%time.Second))
+
+	// Write the key and velocity
+	buf.WriteByte(p.Key)
+	buf.WriteByte(p.Velocity)
+
+	// Write the amplitude and the pan
+	binary.Write(&buf, binary.BigEndian, p.Amplitude)
+	pan := math.Round(math.Max(-1, math.Min(1, float64(p.Pan))) * math.MaxInt8)
+	buf.WriteByte(byte(int8(pan)))
+
+	// Write the timestamp
+	binary.Write(&buf, binary.BigEndian, p.Due)
+
+	// Write 13 bytes of padding
+	buf.Write(make([]byte, 13))
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *DRUM_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid DRUM_Packet data length %d byte", len(data))
+	}
+
+	buf := bytes.NewReader(data)
+
+	var seconds, nanoseconds uint32
+	binary.Read(buf, binary.BigEndian, &seconds)
+	binary.Read(buf, binary.BigEndian, &nanoseconds)
+
+	p.Duration = time.Duration(seconds)*time.Second + time.Duration(nanoseconds)
+
+	p.Key, _ = buf.ReadByte()
+	p.Velocity, _ = buf.ReadByte()
+
+	if err := binary.Read(buf, binary.BigEndian, &p.Amplitude); err != nil {
+		return err
+	}
+
+	pan, _ := buf.ReadByte()
+	p.Pan = float32(int8(pan)) / math.MaxInt8
+
+	return binary.Read(buf, binary.BigEndian, &p.Due)
+}
+
+func (p *DRUM_Packet) String() string {
+	return fmt.Sprintf("DRUM(%d, %d, %d, %f, %.2f)", p.Duration, p.Key, p.Velocity, p.Amplitude, p.Pan)
+}
+
+// Param Packet (PARAM)
+// Sets a parameter of the effects a client plays a voice through, or everything through with AllVoices
+// [0-3] uint32 voice id
+// [4-7] float32 value
+// [8-31] parameter as effect.param, padded with zeros
+type PARAM_Packet struct {
+	Voice uint32
+	Value float32
+	Param string
+}
+
+// AllVoices addresses the effects every voice is played through
+const AllVoices = 0xFFFFFFFF
+
+func (*PARAM_Packet) Type() PacketType {
+	return PARAM
+}
+
+func (p *PARAM_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	// Write the voice and the value
+	binary.Write(&buf, binary.BigEndian, p.Voice)
+	binary.Write(&buf, binary.BigEndian, p.Value)
+
+	// Write the parameter, cut or padded to 24 bytes
+	param := make([]byte, 24)
+	copy(param, p.Param)
+	buf.Write(param)
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *PARAM_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid PARAM_Packet data length %d byte", len(data))
+	}
+
+	p.Voice = binary.BigEndian.Uint32(data[0:4])
+	p.Value = math.Float32frombits(binary.BigEndian.Uint32(data[4:8]))
+	p.Param = string(bytes.TrimRight(data[8:], "\x00"))
+
+	return nil
+}
+
+func (p *PARAM_Packet) String() string {
+	return fmt.Sprintf("PARAM(%d, %q, %f)", p.Voice, p.Param, p.Value)
+}
+
+// Clip Packet (CLIP)
+// Sent by a client to the server when its output went over full scale since the last report
+// [0-3] uint32 number of times the mix went over full scale
+// [4-7] float32 loudest sample of the mix, before the limiter brought it down
+// [8-11] uint32 number of notes cut short to stay under the client's voice limit
+// [12-31] unused
+type CLIP_Packet struct {
+	Clips  uint32
+	Peak   float32
+	Stolen uint32
+}
+
+func (*CLIP_Packet) Type() PacketType {
+	return CLIP
+}
+
+func (p *CLIP_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	binary.Write(&buf, binary.BigEndian, p.Clips)
+	binary.Write(&buf, binary.BigEndian, p.Peak)
+	binary.Write(&buf, binary.BigEndian, p.Stolen)
+
+	// Write 20 bytes of padding
+	buf.Write(make([]byte, 20))
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *CLIP_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid CLIP_Packet data length %d byte", len(data))
+	}
+
+	p.Clips = binary.BigEndian.Uint32(data[0:4])
+	p.Peak = math.Float32frombits(binary.BigEndian.Uint32(data[4:8]))
+	p.Stolen = binary.BigEndian.Uint32(data[8:12])
+
+	return nil
+}
+
+func (p *CLIP_Packet) String() string {
+	return fmt.Sprintf("CLIP(%d, %f, %d)", p.Clips, p.Peak, p.Stolen)
+}
+
+// Status Packet (STATUS)
+// Sent by a client to the server every few seconds while playing, the counts start over with every song
+// [0-3] uint32 notes received
+// [4-7] uint32 notes played
+// [8-11] uint32 notes that arrived late
+// [12-15] uint32 notes that weren't played at all
+// [16-19] int32 slack in milliseconds
+// [20-23] uint32 times the sound card ran out of sound
+// [24-27] int32 clock offset in milliseconds
+// [28] uint8 1 when the slack was measured, 0 when no timed note arrived since the last report
+// [29-31] unused
+type STATUS_Packet struct {
+	Received uint32
+	Played   uint32
+	Late     uint32
+	Dropped  uint32
+
+	// Slack is how much later the latest of the notes since the last report could have arrived
+	// without being late, negative when it was. It is only measured when HasSlack is set.
+	Slack    time.Duration
+	HasSlack bool
+
+	Underruns uint32
+
+	// Offset is the client's clock minus the server's, plus the quickest the network delivered a note
+	Offset time.Duration
+}
+
+func (*STATUS_Packet) Type() PacketType {
+	return STATUS
+}
+
+func (p *STATUS_Packet) Serialize() []byte {
+	// Create a buffer
+	buf := bytes.Buffer{}
+
+	binary.Write(&buf, binary.BigEndian, p.Received)
+	binary.Write(&buf, binary.BigEndian, p.Played)
+	binary.Write(&buf, binary.BigEndian, p.Late)
+	binary.Write(&buf, binary.BigEndian, p.Dropped)
+	binary.Write(&buf, binary.BigEndian, int32(p.Slack/time.Millisecond))
+	binary.Write(&buf, binary.BigEndian, p.Underruns)
+	binary.Write(&buf, binary.BigEndian, int32(p.Offset/time.Millisecond))
+
+	// Write the flag and 3 bytes of padding
+	var hasSlack byte
+	if p.HasSlack {
+		hasSlack = 1
+	}
+	buf.WriteByte(hasSlack)
+	buf.Write(make([]byte, 3))
+
+	// Return the buffer
+	return buf.Bytes()
+}
+
+func (p *STATUS_Packet) DeSerialize(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("invalid STATUS_Packet data length %d byte", len(data))
+	}
+
+	p.Received = binary.BigEndian.Uint32(data[0:4])
+	p.Played = binary.BigEndian.Uint32(data[4:8])
+	p.Late = binary.BigEndian.Uint32(data[8:12])
+	p.Dropped = binary.BigEndian.Uint32(data[12:16])
+	p.Slack = time.Duration(int32(binary.BigEndian.Uint32(data[16:20]))) * time.Millisecond
+	p.Underruns = binary.BigEndian.Uint32(data[20:24])
+	p.Offset = time.Duration(int32(binary.BigEndian.Uint32(data[24:28]))) * time.Millisecond
+	p.HasSlack = data[28] != 0
+
+	return nil
+}
+
+func (p *STATUS_Packet) String() string {
+	return fmt.Sprintf("STATUS(%d received, %d played, %d late, %d dropped, slack %s, %d underruns, offset %v)",
+		p.Received, p.Played, p.Late, p.Dropped, p.SlackString(), p.Underruns, p.Offset)
+}
+
+// SlackString is the slack, or "-" when it wasn't measured
+func (p *STATUS_Packet) SlackString() string {
+	if !p.HasSlack {
+		return "-"
+	}
+	return p.Slack.String()
+}
+
 type UNKNOWN_Packet []byte
 
 func (UNKNOWN_Packet) Type() PacketType {
@@ -1,7 +1,9 @@
 package shared
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 )
@@ -13,6 +15,7 @@ func TestPlay(t *testing.T) {
 		Frequency: 440,
 		Amplitude: 0.5,
 		Voice:     1,
+		Key:       69,
 	}
 	fmt.Println(play)
 
@@ -39,4 +42,271 @@ func TestPlay(t *testing.T) {
 	if p.Voice != play.Voice {
 		t.Errorf("Expected voice %v, got %v", play.Voice, p.Voice)
 	}
+
+	if p.Key != play.Key {
+		t.Errorf("Expected key %v, got %v", play.Key, p.Key)
+	}
+}
+
+func TestStop(t *testing.T) {
+	// keys 0 and 1 both round down to 8Hz, the key tells them apart
+	stop := STOP_Packet{Frequency: 8, Key: 1}
+
+	b := stop.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &STOP_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Frequency != stop.Frequency {
+		t.Errorf("Expected frequency %v, got %v", stop.Frequency, p.Frequency)
+	}
+
+	if p.Key != stop.Key {
+		t.Errorf("Expected key %v, got %v", stop.Key, p.Key)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	latency := LATENCY_Packet{Latency: time.Millisecond * 135}
+
+	p := &LATENCY_Packet{}
+	err := p.DeSerialize(latency.Serialize())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Latency != latency.Latency {
+		t.Errorf("Expected latency %v, got %v", latency.Latency, p.Latency)
+	}
+}
+
+func TestPlayEnvelope(t *testing.T) {
+	play := PLAY_Packet{
+		Duration:  time.Second,
+		Frequency: 220,
+		Amplitude: 0.25,
+		Envelope: &Envelope{
+			Attack:  time.Millisecond * 20,
+			Decay:   time.Millisecond * 100,
+			Sustain: 0.6,
+			Release: time.Second * 5, // longer than fits, should be clamped
+		},
+	}
+
+	p := &PLAY_Packet{}
+	err := p.DeSerialize(play.Serialize())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Envelope == nil {
+		t.Fatal("Expected an envelope")
+	}
+
+	if p.Envelope.Attack != play.Envelope.Attack || p.Envelope.Decay != play.Envelope.Decay {
+		t.Errorf("Expected attack %v and decay %v, got %v and %v", play.Envelope.Attack, play.Envelope.Decay, p.Envelope.Attack, p.Envelope.Decay)
+	}
+
+	if math.Abs(float64(p.Envelope.Sustain-play.Envelope.Sustain)) > 1.0/255 {
+		t.Errorf("Expected sustain %v, got %v", play.Envelope.Sustain, p.Envelope.Sustain)
+	}
+
+	if p.Envelope.Release != envelopeStep*255 {
+		t.Errorf("Expected release %v, got %v", envelopeStep*255, p.Envelope.Release)
+	}
+
+	// no envelope stays no envelope
+	play.Envelope = nil
+	err = p.DeSerialize(play.Serialize())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Envelope != nil {
+		t.Errorf("Expected no envelope, got %v", *p.Envelope)
+	}
+}
+
+func TestPlayInstrument(t *testing.T) {
+	play := PLAY_Packet{
+		Duration:   time.Second,
+		Frequency:  440,
+		Amplitude:  0.5,
+		Envelope:   &Envelope{Attack: time.Millisecond * 10, Sustain: 1},
+		Instrument: &Instrument{Program: 40, Velocity: 100},
+		Pan:        -0.5,
+		Due:        Timestamp(time.Now()),
+	}
+
+	b := play.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &PLAY_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Instrument == nil || *p.Instrument != *play.Instrument {
+		t.Errorf("Expected instrument %v, got %v", *play.Instrument, p.Instrument)
+	}
+
+	if p.Envelope == nil {
+		t.Error("Expected the envelope to be kept")
+	}
+
+	if math.Abs(float64(p.Pan-play.Pan)) > 1.0/127 {
+		t.Errorf("Expected pan %v, got %v", play.Pan, p.Pan)
+	}
+
+	if p.Due != play.Due {
+		t.Errorf("Expected timestamp %v, got %v", play.Due, p.Due)
+	}
+
+	// no instrument stays no instrument
+	play.Instrument = nil
+	err = p.DeSerialize(play.Serialize())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if p.Instrument != nil {
+		t.Errorf("Expected no instrument, got %v", *p.Instrument)
+	}
+}
+
+func TestTimbre(t *testing.T) {
+	timbre := TIMBRE_Packet{Voice: 3, Name: "triangle"}
+
+	b := timbre.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &TIMBRE_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if *p != timbre {
+		t.Errorf("Expected %v, got %v", timbre, *p)
+	}
+}
+
+func TestDrum(t *testing.T) {
+	drum := DRUM_Packet{Duration: time.Millisecond * 250, Key: 38, Velocity: 90, Amplitude: 0.4, Pan: -1, Due: 12345}
+
+	b := drum.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &DRUM_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if *p != drum {
+		t.Errorf("Expected %v, got %v", drum, *p)
+	}
+}
+
+func TestParam(t *testing.T) {
+	param := PARAM_Packet{Voice: AllVoices, Value: 120, Param: "highpass.cutoff"}
+
+	b := param.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &PARAM_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if *p != param {
+		t.Errorf("Expected %v, got %v", param, *p)
+	}
+}
+
+func TestClip(t *testing.T) {
+	clip := CLIP_Packet{Clips: 3, Peak: 1.7, Stolen: 12}
+
+	b := clip.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &CLIP_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if *p != clip {
+		t.Errorf("Expected %v, got %v", clip, *p)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	status := STATUS_Packet{
+		Received:  120,
+		Played:    117,
+		Late:      4,
+		Dropped:   3,
+		Slack:     -12 * time.Millisecond,
+		HasSlack:  true,
+		Underruns: 1,
+		Offset:    -1500 * time.Millisecond,
+	}
+
+	b := status.Serialize()
+	if len(b) != 32 {
+		t.Fatalf("Expected 32 bytes, got %d", len(b))
+	}
+
+	p := &STATUS_Packet{}
+	err := p.DeSerialize(b)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if *p != status {
+		t.Errorf("Expected %v, got %v", status, *p)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	// the difference survives the timestamps wrapping around
+	before := time.Unix(0, 0).Add(time.Millisecond * (math.MaxUint32 - 10))
+	after := before.Add(50 * time.Millisecond)
+
+	if d := int32(Timestamp(after) - Timestamp(before)); d != 50 {
+		t.Errorf("Expected 50ms between the timestamps, got %d", d)
+	}
+}
+
+func TestPing(t *testing.T) {
+	ping := RandomPing()
+
+	p := &PING_Packet{}
+	err := p.DeSerialize(ping.Serialize())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !bytes.Equal(*p, ping) {
+		t.Errorf("Expected %v, got %v", ping, *p)
+	}
 }